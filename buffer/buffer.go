@@ -2,6 +2,7 @@ package buffer
 
 import (
 	"bufio"
+	"errors"
 	"io"
 	"strings"
 	"sync"
@@ -13,6 +14,8 @@ type Buffer struct {
 
 	onRead   func(string)
 	onUpdate func(CommandResponse)
+	onError  func(error)
+	onEvent  func(Event)
 
 	h Handler
 
@@ -21,12 +24,15 @@ type Buffer struct {
 	readQ     *Queue
 	writeQ    *Queue
 	priorityQ *Queue
+	directQ   *Queue
 	onReadQ   *Queue
 	onUpdateQ *Queue
 	metaQ     *Queue
 	ctrlCh    chan string
 
-	mx sync.Mutex
+	done chan struct{}
+	wg   sync.WaitGroup
+	mx   sync.Mutex
 }
 
 type CommandResponse struct {
@@ -36,9 +42,16 @@ type CommandResponse struct {
 	Sent   bool
 	Done   bool
 
+	// Resend, if returned by a Handler, will cause the item to be written again
+	// ahead of any other pending data. It is not reported to OnUpdate.
+	Resend bool
+
 	Err error
 }
 
+// ErrReset is reported for queued commands that are discarded by a buffer reset (e.g. Grbl's `\x18`).
+var ErrReset = errors.New("discarded by reset")
+
 func NewBuffer(cfg Config) *Buffer {
 	b := &Buffer{
 		cfg: cfg.FlowConfig().WithDefaults(),
@@ -46,9 +59,11 @@ func NewBuffer(cfg Config) *Buffer {
 		h:   cfg.Handler,
 
 		ctrlCh:    make(chan string),
+		done:      make(chan struct{}),
 		readQ:     NewQueue(),
 		writeQ:    NewQueue(),
 		priorityQ: NewQueue(),
+		directQ:   NewQueue(),
 		metaQ:     NewQueue(),
 
 		onReadQ:   NewQueue(),
@@ -56,70 +71,139 @@ func NewBuffer(cfg Config) *Buffer {
 
 		onRead:   cfg.OnRead,
 		onUpdate: cfg.OnUpdate,
+		onError:  cfg.OnError,
+		onEvent:  cfg.OnEvent,
 	}
 	b.writeQ.SetCondition(func(item interface{}) bool { return b.h.CheckBuffer(item.(QueueItem).Data) })
 	b.priorityQ.SetCondition(func(item interface{}) bool { return b.h.CheckBuffer(item.(QueueItem).Data) })
 
+	if i, ok := b.h.(Initializer); ok {
+		for _, cmd := range i.InitCommands() {
+			b.queueLine(QueueItem{Data: cmd}, false)
+		}
+	}
+
+	b.wg.Add(3)
 	go b.readLoop()
 	go b.loop()
 	go b.callbackLoop()
 
 	if cfg.PollInterval > 0 {
+		b.wg.Add(1)
 		go b.pollLoop(cfg.PollInterval)
 	}
 
 	return b
 }
 func (b *Buffer) callbackLoop() {
+	defer b.wg.Done()
 	for {
 		select {
-		case line := <-b.onReadQ.Data():
-			b.onRead(line.(string))
+		case <-b.done:
+			return
+		case item := <-b.onReadQ.Data():
+			b.dispatchRead(item)
 		case item := <-b.onUpdateQ.Data():
 			b.onUpdate(item.(CommandResponse))
 		}
 	}
 }
 
+// dispatchRead handles an item from onReadQ, which holds both raw lines and the
+// events parsed from them so that they are delivered in order.
+func (b *Buffer) dispatchRead(item interface{}) {
+	switch t := item.(type) {
+	case string:
+		b.onRead(t)
+	case Event:
+		if b.onEvent != nil {
+			b.onEvent(t)
+		}
+	}
+}
+
 func (b *Buffer) pollLoop(itvl time.Duration) {
+	defer b.wg.Done()
 	t := time.NewTicker(itvl)
 	defer t.Stop()
 
-	for range t.C {
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-t.C:
+		}
 		cmd := b.h.PollCommand()
 		if cmd == "" {
 			continue
 		}
 		err := b.Queue("", cmd)
 		if err != nil {
-			// TODO: error
-			panic(err)
+			// only fails once closed
+			return
 		}
 	}
 }
 func (b *Buffer) handleRead(line string) {
 	b.onReadQ.Push(line + "\n")
+	var resend []QueueItem
 	for _, resp := range b.h.HandleResponse(line) {
+		if resp.Resend {
+			resend = append(resend, resp.QueueItem)
+			continue
+		}
 		b.onUpdateQ.Push(resp)
 	}
+
+	if e, ok := b.h.(Eventer); ok {
+		for _, ev := range e.ResponseEvents(line) {
+			b.onReadQ.Push(ev)
+		}
+	}
+
+	// unshift in reverse to preserve order
+	for i := len(resend) - 1; i >= 0; i-- {
+		b.priorityQ.UnShift(resend[i])
+	}
 }
 func (b *Buffer) handleMeta(line string) {
-	resp := b.h.HandleMeta(line)
+	var resp string
+	if mw, ok := b.h.(MetaWriter); ok {
+		var items []QueueItem
+		resp, items = mw.HandleMetaWrite(line)
+		for i, item := range items {
+			err := b.queueLine(item, false)
+			if err == nil {
+				continue
+			}
+			for _, item := range items[i:] {
+				b.onUpdateQ.Push(CommandResponse{QueueItem: item, Err: err})
+			}
+			break
+		}
+	} else {
+		resp = b.h.HandleMeta(line)
+	}
 	if resp != "" {
 		b.onReadQ.Push(resp + "\n")
 	}
 }
 func (b *Buffer) handleWrite(item QueueItem) {
+	var dropped []interface{}
 	if b.cfg.IsBufferReset(item.Data) {
-		b.writeQ.Reset()
+		dropped = b.writeQ.Reset()
 	}
 	if fn := b.cfg.IsPartialBufferReset(item.Data); fn != nil {
-		b.writeQ.Filter(func(item interface{}) bool { return fn(item.(QueueItem).Data) })
+		dropped = append(dropped, b.writeQ.Filter(func(item interface{}) bool { return fn(item.(QueueItem).Data) })...)
+	}
+	for _, item := range dropped {
+		b.onUpdateQ.Push(CommandResponse{QueueItem: item.(QueueItem), Err: ErrReset})
 	}
 	_, err := io.WriteString(b.rwc, item.Data)
 	if err != nil {
-		// TODO: error
-		panic(err)
+		b.onUpdateQ.Push(CommandResponse{QueueItem: item, Err: err})
+		b.fail(err)
+		return
 	}
 	b.onUpdateQ.Push(CommandResponse{
 		QueueItem: item,
@@ -132,12 +216,14 @@ func (b *Buffer) handleWrite(item QueueItem) {
 }
 
 func (b *Buffer) loop() {
-
+	defer b.wg.Done()
 	for {
 		b.priorityQ.ReCheck()
 		b.writeQ.ReCheck()
 
 		select {
+		case <-b.done:
+			return
 		case chr := <-b.ctrlCh:
 			b.handleWrite(QueueItem{Data: chr})
 			continue
@@ -160,6 +246,9 @@ func (b *Buffer) loop() {
 		case item := <-b.priorityQ.Data():
 			b.handleWrite(item.(QueueItem))
 			continue
+		case item := <-b.directQ.Data():
+			b.handleWrite(item.(QueueItem))
+			continue
 		case line := <-b.readQ.Data():
 			b.handleRead(line.(string))
 			continue
@@ -167,10 +256,14 @@ func (b *Buffer) loop() {
 		}
 
 		select {
+		case <-b.done:
+			return
 		case chr := <-b.ctrlCh:
 			b.handleWrite(QueueItem{Data: chr})
 		case item := <-b.priorityQ.Data():
 			b.handleWrite(item.(QueueItem))
+		case item := <-b.directQ.Data():
+			b.handleWrite(item.(QueueItem))
 		case item := <-b.writeQ.Data():
 			b.handleWrite(item.(QueueItem))
 		case line := <-b.readQ.Data():
@@ -182,6 +275,7 @@ func (b *Buffer) loop() {
 }
 
 func (b *Buffer) readLoop() {
+	defer b.wg.Done()
 	r := bufio.NewScanner(b.rwc)
 	r.Split(b.cfg.SerialDataSplitFunc)
 
@@ -191,17 +285,79 @@ func (b *Buffer) readLoop() {
 		}
 		err := b.readQ.Push(r.Text())
 		if err != nil {
-			// TODO: error
-			panic(err)
+			return
 		}
 	}
+
+	err := r.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	b.fail(err)
 }
 
-func (b *Buffer) Close() error {
-	panic("not implemented")
+// fail will close the Buffer in the background after an I/O error, reporting
+// it via OnError. It is a no-op if the Buffer is already closed.
+func (b *Buffer) fail(err error) {
+	go func() {
+		if errors.Is(b.close(err), ErrClosed) {
+			return
+		}
+		if b.onError != nil {
+			b.onError(err)
+		}
+	}()
+}
+
+// Close will stop all processing and close the underlying ReadWriteCloser. Any commands
+// still pending will be reported through OnUpdate with `ErrClosed` before Close returns.
+func (b *Buffer) Close() error { return b.close(ErrClosed) }
+
+func (b *Buffer) close(reason error) error {
+	b.mx.Lock()
+	select {
+	case <-b.done:
+		b.mx.Unlock()
+		return ErrClosed
+	default:
+	}
+	close(b.done)
+	b.mx.Unlock()
+
+	// closing the port will unblock any pending reads or writes
+	err := b.rwc.Close()
+	b.wg.Wait()
+
+	// nothing else will push to the callback queues at this point, so
+	// flush them in order before reporting aborted commands
+	for b.onReadQ.Len() > 0 {
+		b.dispatchRead(b.onReadQ.Shift())
+	}
+	for b.onUpdateQ.Len() > 0 {
+		b.onUpdate(b.onUpdateQ.Shift().(CommandResponse))
+	}
+
+	var aborted []CommandResponse
+	if a, ok := b.h.(Aborter); ok {
+		aborted = append(aborted, a.Abort(reason)...)
+	}
+	for _, q := range []*Queue{b.priorityQ, b.directQ, b.writeQ} {
+		for _, item := range q.Reset() {
+			aborted = append(aborted, CommandResponse{QueueItem: item.(QueueItem), Err: reason})
+		}
+	}
+	for _, resp := range aborted {
+		b.onUpdate(resp)
+	}
+
+	for _, q := range []*Queue{b.readQ, b.writeQ, b.priorityQ, b.directQ, b.metaQ, b.onReadQ, b.onUpdateQ} {
+		q.Close()
+	}
+
+	return err
 }
 
-func (b *Buffer) queueLine(item QueueItem) error {
+func (b *Buffer) queueLine(item QueueItem, direct bool) error {
 	if b.cfg.IsMeta(item.Data) {
 		return b.metaQ.Push(item.Data)
 	}
@@ -209,6 +365,9 @@ func (b *Buffer) queueLine(item QueueItem) error {
 	item.Data = b.cfg.WrapInput(item.Data)
 	defer b.onUpdateQ.Push(CommandResponse{QueueItem: item, Queued: true})
 
+	if direct || b.cfg.IsRealtime(item.Data) {
+		return b.directQ.Push(item)
+	}
 	if b.cfg.IsControl(item.Data) {
 		return b.priorityQ.Push(item)
 	}
@@ -217,13 +376,32 @@ func (b *Buffer) queueLine(item QueueItem) error {
 }
 
 func (b *Buffer) WriteQueueLen() int {
-	return b.priorityQ.Len() + b.writeQ.Len()
+	return b.priorityQ.Len() + b.directQ.Len() + b.writeQ.Len()
 }
 
-func (b *Buffer) Queue(id, data string) error {
+// Queue will split `data` into lines and queue them to be written once the Handler
+// reports room in the buffer.
+func (b *Buffer) Queue(id, data string) error { return b.queue(id, data, false) }
+
+// QueueDirect works like Queue, but lines are written as soon as possible, bypassing
+// the Handler's CheckBuffer flow control, unless the FlowConfig sets DisableDirect.
+func (b *Buffer) QueueDirect(id, data string) error { return b.queue(id, data, true) }
+
+func (b *Buffer) queue(id, data string, direct bool) error {
+	if line := strings.TrimRight(data, "\r\n"); !strings.ContainsAny(line, "\r\n") && b.cfg.IsMeta(line) {
+		// a single meta command may carry a payload (e.g. JSON) that must not be
+		// sanitized like normal input
+		return b.metaQ.Push(line)
+	}
+
+	direct = direct && !b.cfg.DisableDirect
 	ctrl, data := b.cfg.SplitControlChars(data)
 	for _, chr := range ctrl {
-		b.ctrlCh <- string(chr)
+		select {
+		case <-b.done:
+			return ErrClosed
+		case b.ctrlCh <- string(chr):
+		}
 	}
 
 	s := bufio.NewScanner(strings.NewReader(data))
@@ -237,7 +415,7 @@ func (b *Buffer) Queue(id, data string) error {
 	}
 
 	if len(lines) == 1 {
-		return b.queueLine(QueueItem{ID: id, Data: lines[0]})
+		return b.queueLine(QueueItem{ID: id, Data: lines[0]}, direct)
 	}
 
 	for i, line := range lines {
@@ -246,7 +424,7 @@ func (b *Buffer) Queue(id, data string) error {
 			Seq:    i + 1,
 			SeqMax: len(lines),
 			Data:   line,
-		})
+		}, direct)
 		if err != nil {
 			return err
 		}
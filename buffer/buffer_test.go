@@ -0,0 +1,256 @@
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPort is a ReadWriteCloser standing in for a serial port.
+type testPort struct {
+	in  *io.PipeReader // data from the device
+	out *io.PipeWriter // data written by the Buffer
+}
+
+func (p testPort) Read(b []byte) (int, error)  { return p.in.Read(b) }
+func (p testPort) Write(b []byte) (int, error) { return p.out.Write(b) }
+func (p testPort) Close() error {
+	p.in.Close()
+	return p.out.Close()
+}
+
+// testHandler only allows writes while `free` is set.
+type testHandler struct {
+	Default
+	cfg FlowConfig
+
+	mx      sync.Mutex
+	free    bool
+	pending []QueueItem
+}
+
+func (h *testHandler) FlowConfig() FlowConfig { return h.cfg }
+func (h *testHandler) CheckBuffer(string) bool {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	return h.free
+}
+func (h *testHandler) HandleInput(input QueueItem) []CommandResponse {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	h.pending = append(h.pending, input)
+	return nil
+}
+func (h *testHandler) Abort(err error) []CommandResponse {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	var res []CommandResponse
+	for _, item := range h.pending {
+		res = append(res, CommandResponse{QueueItem: item, Err: err})
+	}
+	h.pending = nil
+	return res
+}
+
+type testBuffer struct {
+	*Buffer
+
+	device  *io.PipeWriter
+	written chan string
+
+	mx      sync.Mutex
+	updates []CommandResponse
+	reads   []string
+}
+
+func newTestBuffer(h Handler) *testBuffer {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	tb := &testBuffer{
+		device:  inW,
+		written: make(chan string, 100),
+	}
+	go func() {
+		s := bufio.NewScanner(outR)
+		for s.Scan() {
+			tb.written <- s.Text()
+		}
+		close(tb.written)
+	}()
+	tb.Buffer = NewBuffer(Config{
+		ReadWriteCloser: testPort{in: inR, out: outW},
+		Handler:         h,
+		OnRead: func(line string) {
+			tb.mx.Lock()
+			tb.reads = append(tb.reads, line)
+			tb.mx.Unlock()
+		},
+		OnUpdate: func(resp CommandResponse) {
+			tb.mx.Lock()
+			tb.updates = append(tb.updates, resp)
+			tb.mx.Unlock()
+		},
+	})
+	return tb
+}
+
+// errors returns the ID and error of every failed update.
+func (tb *testBuffer) errors() map[string]error {
+	tb.mx.Lock()
+	defer tb.mx.Unlock()
+	res := make(map[string]error)
+	for _, resp := range tb.updates {
+		if resp.Err != nil {
+			res[resp.ID] = resp.Err
+		}
+	}
+	return res
+}
+
+func TestBufferQueueDirect(t *testing.T) {
+	b := newTestBuffer(&testHandler{})
+	require.NoError(t, b.QueueDirect("a", "G0"))
+	select {
+	case line := <-b.written:
+		assert.Equal(t, "G0", line)
+	case <-time.After(time.Second):
+		t.Fatal("direct line was not written")
+	}
+	assert.NoError(t, b.Close())
+
+	b = newTestBuffer(&testHandler{cfg: FlowConfig{DisableDirect: true}})
+	require.NoError(t, b.QueueDirect("a", "G0"))
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, b.Close())
+	for line := range b.written {
+		t.Errorf("unexpected write: %q", line)
+	}
+	assert.Equal(t, map[string]error{"a": ErrClosed}, b.errors())
+}
+
+func TestBufferClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	h := &testHandler{free: true}
+	b := newTestBuffer(h)
+	require.NoError(t, b.Queue("a", "G0"))
+	select {
+	case line := <-b.written:
+		assert.Equal(t, "G0", line)
+	case <-time.After(time.Second):
+		t.Fatal("line was not written")
+	}
+
+	h.mx.Lock()
+	h.free = false
+	h.mx.Unlock()
+	require.NoError(t, b.Queue("b", "G1"))
+
+	assert.NoError(t, b.Close())
+	assert.Equal(t, ErrClosed, b.Close())
+	assert.Equal(t, ErrClosed, b.Queue("c", "G2"))
+
+	// everything reported before Close returned, in order
+	b.mx.Lock()
+	var a []CommandResponse
+	for _, resp := range b.updates {
+		if resp.ID == "a" {
+			a = append(a, resp)
+		}
+	}
+	b.mx.Unlock()
+	require.Len(t, a, 3)
+	assert.True(t, a[0].Queued)
+	assert.True(t, a[1].Sent)
+	assert.Equal(t, ErrClosed, a[2].Err)
+	assert.Equal(t, map[string]error{"a": ErrClosed, "b": ErrClosed}, b.errors())
+
+	for line := range b.written {
+		t.Errorf("unexpected write: %q", line)
+	}
+
+	// goroutines may take a moment to be cleaned up after returning
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before, "goroutines still running")
+}
+
+type initHandler struct {
+	testHandler
+}
+
+func (h *initHandler) InitCommands() []string { return []string{"init1", "init2"} }
+
+func TestBufferInitCommands(t *testing.T) {
+	b := newTestBuffer(&initHandler{testHandler{free: true}})
+	defer b.Close()
+	require.NoError(t, b.Queue("a", "G0"))
+
+	for _, exp := range []string{"init1", "init2", "G0"} {
+		select {
+		case line := <-b.written:
+			assert.Equal(t, exp, line)
+		case <-time.After(time.Second):
+			t.Fatalf("%s was not written", exp)
+		}
+	}
+}
+
+type metaHandler struct {
+	testHandler
+}
+
+func (h *metaHandler) HandleMeta(cmd string) string { return "meta: " + cmd }
+
+func TestBufferMeta(t *testing.T) {
+	h := &metaHandler{testHandler{cfg: FlowConfig{
+		IsMeta:            func(cmd string) bool { return strings.HasPrefix(cmd, "*") },
+		SplitControlChars: SplitStaticControlChars("?!"),
+		InputSplitFunc: func(data []byte, atEOF bool) (int, []byte, error) {
+			adv, tok, err := bufio.ScanLines(data, atEOF)
+			return adv, bytes.ReplaceAll(tok, []byte(" "), nil), err
+		},
+	}}}
+	b := newTestBuffer(h)
+	defer b.Close()
+
+	// payload is passed through untouched
+	require.NoError(t, b.Queue("", `*restore*{"a": "b?!"}`+"\n"))
+	assert.Eventually(t, func() bool {
+		b.mx.Lock()
+		defer b.mx.Unlock()
+		return len(b.reads) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{`meta: *restore*{"a": "b?!"}` + "\n"}, b.reads)
+}
+
+func TestBufferMeta_MultiLine(t *testing.T) {
+	h := &metaHandler{testHandler{free: true, cfg: FlowConfig{
+		IsMeta: func(cmd string) bool { return strings.HasPrefix(cmd, "*") },
+	}}}
+	b := newTestBuffer(h)
+	defer b.Close()
+
+	require.NoError(t, b.Queue("a", "*status*\nG0 X1\n"))
+	select {
+	case line := <-b.written:
+		assert.Equal(t, "G0 X1", line)
+	case <-time.After(time.Second):
+		t.Fatal("G0 X1 was not written")
+	}
+	assert.Eventually(t, func() bool {
+		b.mx.Lock()
+		defer b.mx.Unlock()
+		return len(b.reads) == 1 && b.reads[0] == "meta: *status*\n"
+	}, time.Second, 10*time.Millisecond)
+}
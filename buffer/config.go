@@ -11,5 +11,12 @@ type Config struct {
 	OnRead   func(string)
 	OnUpdate (func(CommandResponse))
 
+	// OnError is called if the port fails with an I/O error (e.g. the device was
+	// unplugged). The Buffer will already be closed when it is called.
+	OnError func(error)
+
+	// OnEvent is called with any events produced by a Handler that implements Eventer.
+	OnEvent func(Event)
+
 	PollInterval time.Duration
 }
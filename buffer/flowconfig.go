@@ -23,6 +23,10 @@ type FlowConfig struct {
 	// WrapInput is used to wrap a raw command for sending. The default is to append a newline (`\n`).
 	WrapInput func(string) string
 
+	// DisableDirect will queue lines sent with QueueDirect normally. It should be set if
+	// lines must be written in the order they were queued (e.g. WrapInput assigns line numbers).
+	DisableDirect bool
+
 	// InputSplitFunc can be specified to override using bufio.ScanLines for input commands.
 	InputSplitFunc bufio.SplitFunc
 
@@ -33,6 +37,11 @@ type FlowConfig struct {
 	// any other pending data. Control commands are sent even in an error state.
 	IsControl func(cmd string) bool
 
+	// IsRealtime should return true if a full line must be written as soon as possible,
+	// bypassing CheckBuffer (e.g. Smoothie's `!!` halt). Single characters should use
+	// SplitControlChars instead.
+	IsRealtime func(cmd string) bool
+
 	// IsMeta should return true if a command is intended for the buffer
 	// handler and not the actual serial port. (e.g. `*init*`) It will
 	// be passed to `HandleMeta` instead of written to the port. Meta commands
@@ -62,6 +71,12 @@ func (cfg FlowConfig) WithDefaults() FlowConfig {
 	if cfg.IsControl == nil {
 		cfg.IsControl = func(string) bool { return false }
 	}
+	if cfg.IsRealtime == nil {
+		cfg.IsRealtime = func(string) bool { return false }
+	}
+	if cfg.IsMeta == nil {
+		cfg.IsMeta = func(string) bool { return false }
+	}
 	if cfg.IsBufferReset == nil {
 		cfg.IsBufferReset = func(string) bool { return false }
 	}
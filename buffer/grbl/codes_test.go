@@ -0,0 +1,26 @@
+package grbl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseError(t *testing.T) {
+	assert.Equal(t, Error{Code: 20, Description: errorCodes[20]}, parseError("error:20"))
+	assert.Equal(t, "error:9: G-code locked out during alarm or jog state.", parseError("error:9").Error())
+	assert.Equal(t, Error{Code: 999, Description: "Unknown error."}, parseError("error:999"))
+	assert.EqualError(t, parseError("error: Bad number format"), "error: Bad number format")
+}
+
+func TestParseAlarm(t *testing.T) {
+	a, ok := parseAlarm("ALARM:1")
+	assert.True(t, ok)
+	assert.Equal(t, 1, a.Code)
+	assert.Equal(t, alarmCodes[1], a.Description)
+
+	_, ok = parseAlarm("ALARM:Hard/soft limit")
+	assert.False(t, ok)
+	_, ok = parseAlarm("ok")
+	assert.False(t, ok)
+}
@@ -0,0 +1,165 @@
+package grbl
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/mastercactapus/yaspjs/buffer"
+)
+
+// ParserState is the G-code parser state reported by `$G` (`[GC:...]`).
+type ParserState struct {
+	// Modes are the active modal commands (e.g. `G0`, `G54`, `M5`).
+	Modes []string
+
+	Tool    int
+	Feed    float64
+	Spindle float64
+}
+
+// ProbeResult is the last probe cycle result (`[PRB:...]`).
+type ProbeResult struct {
+	Pos     []float64
+	Success bool
+}
+
+// Offset is a coordinate offset reported by `$#` (e.g. `[G54:...]` or `[TLO:...]`).
+type Offset struct {
+	Name string
+	Pos  []float64
+}
+
+// BuildInfo is the version and build options reported by `$I`.
+type BuildInfo struct {
+	Version string
+	Info    string `json:",omitempty"`
+	Options string `json:",omitempty"`
+
+	PlannerBlocks int `json:",omitempty"`
+	RXBytes       int `json:",omitempty"`
+}
+
+// Message is a feedback message (`[MSG:...]`).
+type Message struct {
+	Text string
+}
+
+// feedback caches the last value of each bracketed push message.
+type feedback struct {
+	parserState ParserState
+	probe       ProbeResult
+	offsets     map[string][]float64
+	buildInfo   BuildInfo
+}
+
+// splitFeedback returns the name and value of a `[NAME:value]` message.
+func splitFeedback(data string) (string, string, bool) {
+	if !strings.HasPrefix(data, "[") || !strings.HasSuffix(data, "]") {
+		return "", "", false
+	}
+	parts := strings.SplitN(data[1:len(data)-1], ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func parseParserState(val string) ParserState {
+	var ps ParserState
+	for _, word := range strings.Fields(val) {
+		switch word[0] {
+		case 'T':
+			ps.Tool, _ = strconv.Atoi(word[1:])
+		case 'F':
+			ps.Feed, _ = strconv.ParseFloat(word[1:], 64)
+		case 'S':
+			ps.Spindle, _ = strconv.ParseFloat(word[1:], 64)
+		default:
+			ps.Modes = append(ps.Modes, word)
+		}
+	}
+	return ps
+}
+
+func parseProbe(val string) ProbeResult {
+	parts := strings.SplitN(val, ":", 2)
+	var res ProbeResult
+	res.Pos = parseFloats(strings.Split(parts[0], ","))
+	res.Success = len(parts) == 2 && parts[1] == "1"
+	return res
+}
+
+func isOffsetName(name string) bool {
+	switch name {
+	case "G28", "G30", "G92", "TLO":
+		return true
+	}
+	return strings.HasPrefix(name, "G5") && len(name) == 3
+}
+
+// handleFeedback will parse and cache a bracketed push message, returning an event if it is recognized.
+func (f *feedback) handleFeedback(data string) (buffer.Event, bool) {
+	name, val, ok := splitFeedback(data)
+	if !ok {
+		return buffer.Event{}, false
+	}
+
+	switch {
+	case name == "MSG":
+		return buffer.Event{Type: "Message", Value: Message{Text: val}}, true
+	case name == "GC":
+		f.parserState = parseParserState(val)
+		return buffer.Event{Type: "ParserState", Value: f.parserState}, true
+	case name == "PRB":
+		f.probe = parseProbe(val)
+		return buffer.Event{Type: "Probe", Value: f.probe}, true
+	case isOffsetName(name):
+		pos := parseFloats(strings.Split(val, ","))
+		if f.offsets == nil {
+			f.offsets = make(map[string][]float64)
+		}
+		f.offsets[name] = pos
+		return buffer.Event{Type: "Offset", Value: Offset{Name: name, Pos: pos}}, true
+	case name == "VER":
+		parts := strings.SplitN(val, ":", 2)
+		f.buildInfo.Version = parts[0]
+		if len(parts) == 2 {
+			f.buildInfo.Info = parts[1]
+		}
+		return buffer.Event{Type: "BuildInfo", Value: f.buildInfo}, true
+	case name == "OPT":
+		parts := strings.Split(val, ",")
+		f.buildInfo.Options = parts[0]
+		if len(parts) >= 3 {
+			f.buildInfo.PlannerBlocks, _ = strconv.Atoi(parts[1])
+			f.buildInfo.RXBytes, _ = strconv.Atoi(parts[2])
+		}
+		return buffer.Event{Type: "BuildInfo", Value: f.buildInfo}, true
+	}
+
+	return buffer.Event{}, false
+}
+
+// handleFeedbackMeta returns the cached value for `*parserstate*`, `*probe*`, `*offsets*` or `*buildinfo*` as JSON.
+func (f *feedback) handleFeedbackMeta(cmd string) (string, bool) {
+	var v interface{}
+	switch cmd {
+	case "*parserstate*":
+		v = f.parserState
+	case "*probe*":
+		v = f.probe
+	case "*offsets*":
+		v = f.offsets
+	case "*buildinfo*":
+		v = f.buildInfo
+	default:
+		return "", false
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errorJSON(err), true
+	}
+	return string(data), true
+}
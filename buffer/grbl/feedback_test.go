@@ -0,0 +1,53 @@
+package grbl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedback(t *testing.T) {
+	var f feedback
+
+	e, ok := f.handleFeedback("[GC:G0 G54 G17 G21 G90 G94 M5 M9 T2 F500 S12000]")
+	require.True(t, ok)
+	assert.Equal(t, "ParserState", e.Type)
+	assert.Equal(t, ParserState{
+		Modes:   []string{"G0", "G54", "G17", "G21", "G90", "G94", "M5", "M9"},
+		Tool:    2,
+		Feed:    500,
+		Spindle: 12000,
+	}, e.Value)
+
+	e, ok = f.handleFeedback("[PRB:1.000,2.000,-3.500:1]")
+	require.True(t, ok)
+	assert.Equal(t, ProbeResult{Pos: []float64{1, 2, -3.5}, Success: true}, e.Value)
+	_, ok = f.handleFeedback("[PRB:0.000,0.000,0.000:0]")
+	require.True(t, ok)
+	assert.False(t, f.probe.Success)
+
+	_, ok = f.handleFeedback("[G54:10.000,0.000,-1.000]")
+	require.True(t, ok)
+	_, ok = f.handleFeedback("[TLO:0.500]")
+	require.True(t, ok)
+	assert.Equal(t, map[string][]float64{"G54": {10, 0, -1}, "TLO": {0.5}}, f.offsets)
+
+	f.handleFeedback("[VER:1.1f.20170801:my router]")
+	e, ok = f.handleFeedback("[OPT:VL,15,128]")
+	require.True(t, ok)
+	assert.Equal(t, BuildInfo{Version: "1.1f.20170801", Info: "my router", Options: "VL", PlannerBlocks: 15, RXBytes: 128}, e.Value)
+
+	e, ok = f.handleFeedback("[MSG:Reset to continue]")
+	require.True(t, ok)
+	assert.Equal(t, Message{Text: "Reset to continue"}, e.Value)
+
+	resp, ok := f.handleFeedbackMeta("*probe*")
+	require.True(t, ok)
+	assert.Equal(t, `{"Pos":[0,0,0],"Success":false}`, resp)
+
+	_, ok = f.handleFeedback("[echo:G0]")
+	assert.False(t, ok)
+	_, ok = f.handleFeedback("<Idle>")
+	assert.False(t, ok)
+}
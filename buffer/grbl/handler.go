@@ -3,6 +3,7 @@ package grbl
 import (
 	"errors"
 	"strings"
+	"sync/atomic"
 
 	"github.com/mastercactapus/yaspjs/buffer"
 )
@@ -12,20 +13,46 @@ const grblMax = 125
 type Grbl struct {
 	q *buffer.Queue
 
+	// max is the number of bytes that may be outstanding, accessed atomically.
+	max int32
+
+	// queryRX enables sizing max from the controller's reported RX buffer.
+	queryRX bool
+
+	// settings is the snapshot from `$$`, settingPending is set (atomically) while
+	// a setting write is outstanding. settingsDone is set once `$$` completes, so
+	// that the snapshot is reported by ResponseEvents.
+	settings       map[string]string
+	settingPending int32
+	settingsDone   bool
+
 	feedHold bool
 
 	version    string
 	lastStatus string
+	status     Status
+	feedback
 }
 
-var _ buffer.Handler = &Grbl{}
+var (
+	_ buffer.Handler     = &Grbl{}
+	_ buffer.Aborter     = &Grbl{}
+	_ buffer.Eventer     = &Grbl{}
+	_ buffer.MetaWriter  = &Grbl{}
+	_ buffer.Initializer = &Grbl{}
+)
 
 func filterJog(cmd string) bool { return !strings.HasPrefix(cmd, "$J=") }
 
-func NewHandler() buffer.Handler {
+func NewHandler() buffer.Handler { return New(grblMax) }
+
+// New will return a Grbl handler that allows up to `max` bytes to be outstanding.
+func New(max int) *Grbl {
 	return &Grbl{
 		// all commands will be at least 1 byte + 1 newline, 64*2 = 128 which is already larger than Grbl's 127-byte buffer.
-		q: buffer.NewQueue(),
+		q:        buffer.NewQueue(),
+		max:      int32(max),
+		settings: make(map[string]string),
 	}
 }
 
@@ -33,11 +60,31 @@ func (g *Grbl) Buffer() []interface{} { return g.q.Buffer() }
 
 func (g *Grbl) PollCommand() string { return "?" }
 func (g *Grbl) CheckBuffer(data string) bool {
-	return g.q.ByteLen()+len(data) <= grblMax
+	// settings are written to EEPROM, so they must be sent one at a time
+	if atomic.LoadInt32(&g.settingPending) == 1 {
+		return false
+	}
+	if isSettingWrite(data) {
+		return g.q.Len() == 0
+	}
+
+	return g.q.ByteLen()+len(data) <= g.windowSize()
 }
 func (g *Grbl) IsPaused() bool { return g.feedHold }
 
+func (g *Grbl) HandleMetaWrite(cmd string) (string, []buffer.QueueItem) {
+	if resp, items, ok := g.handleSettingsMeta(cmd); ok {
+		return resp, items
+	}
+
+	return g.HandleMeta(cmd), nil
+}
+
 func (g *Grbl) HandleMeta(cmd string) string {
+	if resp, ok := g.handleFeedbackMeta(cmd); ok {
+		return resp
+	}
+
 	switch cmd {
 	case "*init*":
 		return g.version
@@ -58,13 +105,34 @@ func (g *Grbl) HandleInput(input buffer.QueueItem) []buffer.CommandResponse {
 		return []buffer.CommandResponse{{QueueItem: input, Done: true}}
 	}
 
-	g.q.Push(input)
-	if g.q.ByteLen() > grblMax {
-		panic("overflow")
+	if isSettingWrite(input.Data) {
+		atomic.StoreInt32(&g.settingPending, 1)
 	}
+
+	// may exceed max if written directly (e.g. `sendnobuf`)
+	g.q.Push(input)
 	return nil
 }
 
+func (g *Grbl) Abort(err error) []buffer.CommandResponse {
+	resp := g.Reset(err)
+	g.q.Close()
+	return resp
+}
+
+// Reset will fail all outstanding commands with `err`. It should be called
+// if the controller is reset.
+func (g *Grbl) Reset(err error) []buffer.CommandResponse {
+	atomic.StoreInt32(&g.settingPending, 0)
+	items := g.q.Reset()
+	resp := make([]buffer.CommandResponse, len(items))
+	for i, item := range items {
+		resp[i].QueueItem = item.(buffer.QueueItem)
+		resp[i].Err = err
+	}
+	return resp
+}
+
 func (Grbl) FlowConfig() buffer.FlowConfig {
 	return buffer.FlowConfig{
 		InputSplitFunc:    ScanInput,
@@ -87,29 +155,60 @@ func (Grbl) FlowConfig() buffer.FlowConfig {
 }
 
 func (g *Grbl) HandleResponse(data string) []buffer.CommandResponse {
+	if (data == "ok" || strings.HasPrefix(data, "error:")) && g.q.Len() == 0 {
+		// nothing outstanding (e.g. the port was opened mid-stream)
+		return nil
+	}
 	if data == "ok" {
+		item := g.q.Shift().(buffer.QueueItem)
+		g.handleSettingDone(item, true)
 		return []buffer.CommandResponse{{
-			QueueItem: g.q.Shift().(buffer.QueueItem),
+			QueueItem: item,
 			Done:      true,
 		}}
 	}
 	if strings.HasPrefix(data, "error:") {
+		item := g.q.Shift().(buffer.QueueItem)
+		g.handleSettingDone(item, false)
 		return []buffer.CommandResponse{{
-			QueueItem: g.q.Shift().(buffer.QueueItem),
-			Err:       errors.New(data),
+			QueueItem: item,
+			Err:       parseError(data),
 		}}
 	}
+	if strings.HasPrefix(data, "$") {
+		g.handleSettingResponse(data)
+	}
 	if strings.HasPrefix(data, "Grbl") {
 		g.version = data
-		items := g.q.Reset()
-		resp := make([]buffer.CommandResponse, len(items))
-		for i, item := range items {
-			resp[i].QueueItem = item.(buffer.QueueItem)
-			resp[i].Err = errors.New("reset")
+		resp := g.Reset(errors.New("reset"))
+		if g.queryRX {
+			// the window may have changed (e.g. new firmware)
+			resp = append(resp, buffer.CommandResponse{QueueItem: buffer.QueueItem{Data: rxQuery + "\n"}, Resend: true})
 		}
+		return resp
 	}
 	if strings.HasPrefix(data, "<") {
 		g.lastStatus = data
 	}
+	if g.queryRX {
+		g.handleRXSize(data)
+	}
 	return nil
 }
+
+func (g *Grbl) ResponseEvents(data string) []buffer.Event {
+	if e, ok := g.settingsEvent(); ok {
+		return []buffer.Event{e}
+	}
+	if alarm, ok := parseAlarm(data); ok {
+		return []buffer.Event{{Type: "Alarm", Value: alarm}}
+	}
+	if e, ok := g.handleFeedback(data); ok {
+		return []buffer.Event{e}
+	}
+	if !g.status.Update(data) {
+		return nil
+	}
+
+	return []buffer.Event{{Type: "Status", Value: g.status}}
+}
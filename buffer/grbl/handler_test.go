@@ -0,0 +1,32 @@
+package grbl
+
+import (
+	"testing"
+
+	"github.com/mastercactapus/yaspjs/buffer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrbl_HandleResponse(t *testing.T) {
+	g := New(grblMax)
+	defer g.q.Close()
+
+	// nothing outstanding (e.g. opened mid-stream)
+	assert.Empty(t, g.HandleResponse("ok"))
+	assert.Empty(t, g.HandleResponse("error:9"))
+
+	g.HandleInput(buffer.QueueItem{ID: "a", Data: "G0\n"})
+	g.HandleInput(buffer.QueueItem{ID: "b", Data: "G1\n"})
+
+	resp := g.HandleResponse("ok")
+	if assert.Len(t, resp, 1) {
+		assert.Equal(t, "a", resp[0].ID)
+		assert.True(t, resp[0].Done)
+	}
+	resp = g.HandleResponse("error:9")
+	if assert.Len(t, resp, 1) {
+		assert.Equal(t, "b", resp[0].ID)
+		assert.Error(t, resp[0].Err)
+	}
+	assert.Empty(t, g.HandleResponse("ok"))
+}
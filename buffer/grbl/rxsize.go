@@ -0,0 +1,92 @@
+package grbl
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mastercactapus/yaspjs/buffer"
+)
+
+// rxReserve is subtracted from the reported RX buffer size, matching grblMax
+// for Grbl's 128-byte buffer.
+const rxReserve = 3
+
+// rxQuery requests the build info, including the RX buffer size.
+const rxQuery = "$I"
+
+// NewHALHandler will return a Grbl handler for grblHAL, FluidNC and other controllers that
+// report their RX buffer size. The send window is sized from `$I` (sent on open and after
+// each reset) and the `Bf:` field of status reports.
+func NewHALHandler() buffer.Handler {
+	g := New(grblMax)
+	g.queryRX = true
+	return g
+}
+
+// InitCommands requests the RX buffer size, if enabled.
+func (g *Grbl) InitCommands() []string {
+	if !g.queryRX {
+		return nil
+	}
+	return []string{rxQuery}
+}
+
+func (g *Grbl) windowSize() int { return int(atomic.LoadInt32(&g.max)) }
+
+func (g *Grbl) setRXSize(n int) {
+	if n <= rxReserve {
+		return
+	}
+	atomic.StoreInt32(&g.max, int32(n-rxReserve))
+}
+
+// parseOptRX returns the RX buffer size from a `[OPT:<codes>,<blocks>,<rx bytes>...]` line.
+func parseOptRX(data string) (int, bool) {
+	if !strings.HasPrefix(data, "[OPT:") || !strings.HasSuffix(data, "]") {
+		return 0, false
+	}
+	fields := strings.Split(data[len("[OPT:"):len(data)-1], ",")
+	if len(fields) < 3 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// parseBfRX returns the available RX buffer bytes from the `Bf:<blocks>,<rx bytes>` field of a status report.
+func parseBfRX(data string) (int, bool) {
+	if !strings.HasPrefix(data, "<") || !strings.HasSuffix(data, ">") {
+		return 0, false
+	}
+	for _, field := range strings.Split(data[1:len(data)-1], "|") {
+		if !strings.HasPrefix(field, "Bf:") {
+			continue
+		}
+		parts := strings.Split(field[len("Bf:"):], ",")
+		if len(parts) != 2 {
+			return 0, false
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return 0, false
+		}
+		return n, true
+	}
+	return 0, false
+}
+
+func (g *Grbl) handleRXSize(data string) {
+	if n, ok := parseOptRX(data); ok {
+		g.setRXSize(n)
+		return
+	}
+
+	// with nothing outstanding, the available space is the full buffer
+	if n, ok := parseBfRX(data); ok && g.q.Len() == 0 && n-rxReserve > g.windowSize() {
+		g.setRXSize(n)
+	}
+}
@@ -0,0 +1,55 @@
+package grbl
+
+import (
+	"testing"
+
+	"github.com/mastercactapus/yaspjs/buffer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOptRX(t *testing.T) {
+	check := func(data string, expN int, expOK bool) {
+		t.Helper()
+		n, ok := parseOptRX(data)
+		assert.Equal(t, expOK, ok, data)
+		assert.Equal(t, expN, n, data)
+	}
+
+	check("[OPT:VL,15,128]", 128, true)
+	check("[OPT:VNMSL,35,1024,3,0]", 1024, true)
+	check("[OPT:V,15]", 0, false)
+	check("[VER:1.1f.20170801:]", 0, false)
+}
+
+func TestParseBfRX(t *testing.T) {
+	check := func(data string, expN int, expOK bool) {
+		t.Helper()
+		n, ok := parseBfRX(data)
+		assert.Equal(t, expOK, ok, data)
+		assert.Equal(t, expN, n, data)
+	}
+
+	check("<Idle|MPos:0.000,0.000,0.000|Bf:35,1023|FS:0,0>", 1023, true)
+	check("<Idle|MPos:0.000,0.000,0.000|FS:0,0>", 0, false)
+	check("ok", 0, false)
+}
+
+func TestGrbl_QueryRX(t *testing.T) {
+	assert.Empty(t, New(grblMax).InitCommands())
+
+	g := NewHALHandler().(*Grbl)
+	assert.Equal(t, []string{"$I"}, g.InitCommands())
+	assert.Equal(t, "?", g.PollCommand(), "$I is not sent by polling")
+
+	g.HandleInput(buffer.QueueItem{ID: "a", Data: "G0X1\n"})
+	resp := g.HandleResponse("Grbl 1.1f ['$' for help]")
+	if assert.Len(t, resp, 2) {
+		assert.Equal(t, "a", resp[0].ID)
+		assert.Error(t, resp[0].Err)
+		assert.True(t, resp[1].Resend)
+		assert.Equal(t, "$I\n", resp[1].Data, "queried again after a reset")
+	}
+
+	g.HandleResponse("[OPT:VNMSL,35,1024,3,0]")
+	assert.Equal(t, 1021, g.windowSize())
+}
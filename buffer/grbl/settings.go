@@ -0,0 +1,184 @@
+package grbl
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mastercactapus/yaspjs/buffer"
+)
+
+// restoreID is used for commands queued by `*restore*` so that clients receive updates for them.
+const restoreID = "restore"
+
+// settingsID is used for the `$$` queued by `*settings*`.
+const settingsID = "settings"
+
+var settingRx = regexp.MustCompile(`^\$(\d+)=(\S*)`)
+
+var (
+	settingKeyRx = regexp.MustCompile(`^\d+$`)
+
+	// settingValueRx excludes spaces, control characters and realtime commands
+	settingValueRx = regexp.MustCompile(`^[\x21-\x7e]+$`)
+)
+
+// checkSetting returns an error if the key or value could not be written as a single `$N=value` line.
+func checkSetting(key, val string) error {
+	if !settingKeyRx.MatchString(key) {
+		return fmt.Errorf("invalid setting '%s'", key)
+	}
+	if !settingValueRx.MatchString(val) || strings.ContainsAny(val, "?~!") {
+		return fmt.Errorf("invalid value for setting '%s': %q", key, val)
+	}
+	return nil
+}
+
+// parseSetting returns the number and value from a `$N=value` line, ignoring any
+// trailing description (e.g. Grbl 0.9's `$0=10 (step pulse, usec)`).
+func parseSetting(data string) (string, string, bool) {
+	m := settingRx.FindStringSubmatch(strings.TrimSpace(data))
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+func isSettingWrite(data string) bool {
+	_, _, ok := parseSetting(data)
+	return ok
+}
+
+// settingEqual compares numerically if possible, so that `10` and `10.000` are equal.
+func settingEqual(a, b string) bool {
+	if a == b {
+		return true
+	}
+	af, err := strconv.ParseFloat(a, 64)
+	if err != nil {
+		return false
+	}
+	bf, err := strconv.ParseFloat(b, 64)
+	if err != nil {
+		return false
+	}
+	return af == bf
+}
+
+// Settings is the format used by `*settings*` and accepted by `*restore*`.
+type Settings struct {
+	Settings map[string]string
+}
+
+func (g *Grbl) handleSettingsMeta(cmd string) (string, []buffer.QueueItem, bool) {
+	switch {
+	case cmd == "*settings*":
+		// the snapshot is reported as a `Settings` event once `$$` completes
+		return "", []buffer.QueueItem{{ID: settingsID, Data: "$$"}}, true
+	case strings.HasPrefix(cmd, "*restore*"):
+		resp, items := g.restore(strings.TrimPrefix(cmd, "*restore*"))
+		return resp, items, true
+	}
+
+	return "", nil, false
+}
+
+// restore will return a write for every setting in `data` that differs from the last
+// `$$` snapshot. Setting writes are sent one at a time, see CheckBuffer.
+func (g *Grbl) restore(data string) (string, []buffer.QueueItem) {
+	var saved Settings
+	err := json.Unmarshal([]byte(data), &saved)
+	if err != nil {
+		return errorJSON(fmt.Errorf("parse settings: %w", err)), nil
+	}
+	if len(g.settings) == 0 {
+		return errorJSON(fmt.Errorf("no settings snapshot, send $$ first")), nil
+	}
+
+	keys := make([]string, 0, len(saved.Settings))
+	for key, val := range saved.Settings {
+		err = checkSetting(key, val)
+		if err != nil {
+			return errorJSON(err), nil
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, _ := strconv.Atoi(keys[i])
+		b, _ := strconv.Atoi(keys[j])
+		return a < b
+	})
+
+	var res struct {
+		Restored map[string]string
+	}
+	res.Restored = make(map[string]string)
+	var items []buffer.QueueItem
+	for _, key := range keys {
+		val := saved.Settings[key]
+		if cur, ok := g.settings[key]; ok && settingEqual(cur, val) {
+			continue
+		}
+		res.Restored[key] = val
+		items = append(items, buffer.QueueItem{ID: restoreID, Data: "$" + key + "=" + val})
+	}
+	for i := range items {
+		items[i].Seq = i + 1
+		items[i].SeqMax = len(items)
+	}
+
+	out, err := json.Marshal(res)
+	if err != nil {
+		return errorJSON(err), nil
+	}
+
+	return string(out), items
+}
+
+// handleSettingResponse updates the snapshot from `$$` output, or once a setting write is acknowledged.
+func (g *Grbl) handleSettingResponse(data string) {
+	if key, val, ok := parseSetting(data); ok {
+		g.settings[key] = val
+	}
+}
+
+func (g *Grbl) handleSettingDone(item buffer.QueueItem, ok bool) {
+	if ok && strings.TrimSpace(item.Data) == "$$" {
+		g.settingsDone = true
+		return
+	}
+	if !isSettingWrite(item.Data) {
+		return
+	}
+	atomic.StoreInt32(&g.settingPending, 0)
+	if ok {
+		g.handleSettingResponse(item.Data)
+	}
+}
+
+// settingsEvent returns a copy of the snapshot if `$$` has just completed.
+func (g *Grbl) settingsEvent() (buffer.Event, bool) {
+	if !g.settingsDone {
+		return buffer.Event{}, false
+	}
+	g.settingsDone = false
+
+	s := Settings{Settings: make(map[string]string, len(g.settings))}
+	for key, val := range g.settings {
+		s.Settings[key] = val
+	}
+	return buffer.Event{Type: "Settings", Value: s}, true
+}
+
+func errorJSON(err error) string {
+	var res struct {
+		Error string
+	}
+	res.Error = err.Error()
+	data, _ := json.Marshal(res)
+	return string(data)
+}
@@ -0,0 +1,87 @@
+package grbl
+
+import (
+	"testing"
+
+	"github.com/mastercactapus/yaspjs/buffer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrbl_Restore(t *testing.T) {
+	g := New(grblMax)
+	defer g.q.Close()
+
+	resp, items := g.HandleMetaWrite(`*restore*{"Settings":{"0":"10"}}`)
+	assert.Equal(t, `{"Error":"no settings snapshot, send $$ first"}`, resp)
+	assert.Empty(t, items)
+
+	g.HandleResponse("$0=10")
+	g.HandleResponse("$1=25 (step idle delay, msec)")
+	g.HandleResponse("$110=500.000")
+
+	resp, items = g.HandleMetaWrite("*settings*")
+	assert.Empty(t, resp)
+	assert.Equal(t, []buffer.QueueItem{{ID: settingsID, Data: "$$"}}, items)
+
+	resp, items = g.HandleMetaWrite(`*restore*{"Settings":{"0":"10","1":"255","110":"500","120":"10.5"}}`)
+	assert.Equal(t, `{"Restored":{"1":"255","120":"10.5"}}`, resp)
+	assert.Equal(t, []buffer.QueueItem{
+		{ID: restoreID, Data: "$1=255", Seq: 1, SeqMax: 2},
+		{ID: restoreID, Data: "$120=10.5", Seq: 2, SeqMax: 2},
+	}, items)
+}
+
+func TestGrbl_Settings(t *testing.T) {
+	g := New(grblMax)
+	defer g.q.Close()
+
+	_, items := g.HandleMetaWrite("*settings*")
+	g.HandleInput(items[0])
+	for _, line := range []string{"$0=10", "$1=25"} {
+		assert.Empty(t, g.HandleResponse(line))
+		assert.Empty(t, g.ResponseEvents(line))
+	}
+
+	resp := g.HandleResponse("ok")
+	if assert.Len(t, resp, 1) {
+		assert.Equal(t, settingsID, resp[0].ID)
+		assert.True(t, resp[0].Done)
+	}
+	assert.Equal(t, []buffer.Event{{
+		Type:  "Settings",
+		Value: Settings{Settings: map[string]string{"0": "10", "1": "25"}},
+	}}, g.ResponseEvents("ok"))
+	assert.Empty(t, g.ResponseEvents("ok"), "only reported once")
+
+	// failed `$$` (e.g. alarm lock) reports the error instead
+	g.HandleInput(items[0])
+	resp = g.HandleResponse("error:9")
+	if assert.Len(t, resp, 1) {
+		assert.Error(t, resp[0].Err)
+	}
+	assert.Empty(t, g.ResponseEvents("error:9"))
+}
+
+func TestGrbl_Restore_Invalid(t *testing.T) {
+	g := New(grblMax)
+	defer g.q.Close()
+	g.HandleResponse("$0=10")
+
+	for _, data := range []string{
+		`{"Settings":{"1=255\n$RST=*":"1"}}`,
+		`{"Settings":{"":"1"}}`,
+		`{"Settings":{"N0":"G20"}}`,
+		`{"Settings":{"0":"10\n$RST=*"}}`,
+		`{"Settings":{"0":"10!"}}`,
+		`{"Settings":{"0":"10\u0018"}}`,
+		`{"Settings":{"0":"1 0"}}`,
+		`{"Settings":{"0":""}}`,
+		`{"Settings":{"0":"\u0084"}}`,
+		// the whole restore is rejected, not just the bad entry
+		`{"Settings":{"1":"255","2":"?"}}`,
+	} {
+		resp, items := g.HandleMetaWrite("*restore*" + data)
+		assert.Contains(t, resp, `"Error":"invalid`, data)
+		assert.Empty(t, items, data)
+	}
+}
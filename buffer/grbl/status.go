@@ -0,0 +1,168 @@
+package grbl
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Status is the machine state from Grbl's real-time status reports. Values that
+// are only reported periodically (e.g. WCO and overrides) are merged from previous reports.
+type Status struct {
+	// State is the machine state (e.g. `Idle`, `Run`, `Hold`, `Alarm`).
+	State string
+
+	// SubState is the optional code following the state (e.g. `0` for `Hold:0`).
+	SubState *int `json:",omitempty"`
+
+	MPos []float64 `json:",omitempty"`
+	WPos []float64 `json:",omitempty"`
+	WCO  []float64 `json:",omitempty"`
+
+	Feed    float64
+	Spindle float64
+
+	// PlannerBlocks and RXBytes are the available space reported by `Bf:`.
+	PlannerBlocks int `json:",omitempty"`
+	RXBytes       int `json:",omitempty"`
+
+	// Line is the line number currently executing (`Ln:`).
+	Line int `json:",omitempty"`
+
+	// Override is the feed, rapid and spindle override percentages (`Ov:`).
+	Override []int `json:",omitempty"`
+
+	// Pins is the list of triggered input pins (`Pn:`), e.g. `XYZPDHRS`.
+	Pins string `json:",omitempty"`
+
+	// Accessories is the accessory state (`A:`), e.g. `SFM`.
+	Accessories string `json:",omitempty"`
+}
+
+// parseStatusFields splits a `<...>` report into the state and a map of fields. Both the
+// Grbl 1.1 (`|` separated) and 0.9 (`,` separated) formats are supported.
+func parseStatusFields(data string) (string, map[string][]string, bool) {
+	if !strings.HasPrefix(data, "<") || !strings.HasSuffix(data, ">") {
+		return "", nil, false
+	}
+	data = data[1 : len(data)-1]
+
+	fields := make(map[string][]string)
+	if strings.Contains(data, "|") {
+		parts := strings.Split(data, "|")
+		for _, part := range parts[1:] {
+			kv := strings.SplitN(part, ":", 2)
+			if len(kv) == 1 {
+				fields[kv[0]] = nil
+				continue
+			}
+			fields[kv[0]] = strings.Split(kv[1], ",")
+		}
+		return parts[0], fields, true
+	}
+
+	parts := strings.Split(data, ",")
+	var key string
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, ":", 2)
+		if len(kv) == 2 {
+			key = kv[0]
+			fields[key] = []string{kv[1]}
+			continue
+		}
+		if key != "" {
+			fields[key] = append(fields[key], part)
+		}
+	}
+	return parts[0], fields, true
+}
+
+func parseFloats(vals []string) []float64 {
+	res := make([]float64, len(vals))
+	for i, v := range vals {
+		res[i], _ = strconv.ParseFloat(v, 64)
+	}
+	return res
+}
+
+func parseInts(vals []string) []int {
+	res := make([]int, len(vals))
+	for i, v := range vals {
+		res[i], _ = strconv.Atoi(v)
+	}
+	return res
+}
+
+// Update will merge the status report `data` into the current Status, returning
+// false if it is not a status report.
+func (s *Status) Update(data string) bool {
+	state, fields, ok := parseStatusFields(data)
+	if !ok {
+		return false
+	}
+
+	s.SubState = nil
+	if parts := strings.SplitN(state, ":", 2); len(parts) == 2 {
+		sub, err := strconv.Atoi(parts[1])
+		if err == nil {
+			s.SubState = &sub
+		}
+		state = parts[0]
+	}
+	s.State = state
+
+	if v, ok := fields["WCO"]; ok {
+		s.WCO = parseFloats(v)
+	}
+	if v, ok := fields["Ov"]; ok {
+		s.Override = parseInts(v)
+	}
+	if v, ok := fields["Bf"]; ok && len(v) == 2 {
+		buf := parseInts(v)
+		s.PlannerBlocks, s.RXBytes = buf[0], buf[1]
+	}
+	if v, ok := fields["Ln"]; ok && len(v) == 1 {
+		s.Line, _ = strconv.Atoi(v[0])
+	}
+	if v, ok := fields["FS"]; ok && len(v) >= 1 {
+		fs := parseFloats(v)
+		s.Feed = fs[0]
+		if len(fs) > 1 {
+			s.Spindle = fs[1]
+		}
+	}
+	if v, ok := fields["F"]; ok && len(v) == 1 {
+		s.Feed, _ = strconv.ParseFloat(v[0], 64)
+	}
+
+	// pins and accessories are omitted entirely when none are active
+	s.Pins = strings.Join(fields["Pn"], "")
+	s.Accessories = strings.Join(fields["A"], "")
+
+	mpos, hasM := fields["MPos"]
+	wpos, hasW := fields["WPos"]
+	switch {
+	case hasM && hasW:
+		s.MPos = parseFloats(mpos)
+		s.WPos = parseFloats(wpos)
+	case hasM:
+		s.MPos = parseFloats(mpos)
+		s.WPos = offset(s.MPos, s.WCO, -1)
+	case hasW:
+		s.WPos = parseFloats(wpos)
+		s.MPos = offset(s.WPos, s.WCO, 1)
+	}
+
+	return true
+}
+
+// offset returns `pos + sign*wco` for each axis, or nil if the offset is unknown.
+func offset(pos, wco []float64, sign float64) []float64 {
+	if len(wco) != len(pos) {
+		return nil
+	}
+	res := make([]float64, len(pos))
+	for i := range pos {
+		res[i] = pos[i] + sign*wco[i]
+	}
+	return res
+}
@@ -0,0 +1,80 @@
+package grbl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatus_Update(t *testing.T) {
+	var s Status
+
+	require.True(t, s.Update("<Idle|MPos:1.000,2.000,3.000|FS:0,0|WCO:0.500,0.000,-1.000>"))
+	assert.Equal(t, "Idle", s.State)
+	assert.Nil(t, s.SubState)
+	assert.Equal(t, []float64{1, 2, 3}, s.MPos)
+	assert.Equal(t, []float64{0.5, 2, 4}, s.WPos)
+
+	// WCO is not sent every time, so the last one should be used
+	require.True(t, s.Update("<Hold:0|MPos:2.000,2.000,3.000|FS:500,12000|Pn:XZ|Ov:100,50,120|A:SF>"))
+	assert.Equal(t, "Hold", s.State)
+	require.NotNil(t, s.SubState)
+	assert.Equal(t, 0, *s.SubState)
+	assert.Equal(t, []float64{1.5, 2, 4}, s.WPos)
+	assert.Equal(t, 500.0, s.Feed)
+	assert.Equal(t, 12000.0, s.Spindle)
+	assert.Equal(t, "XZ", s.Pins)
+	assert.Equal(t, []int{100, 50, 120}, s.Override)
+	assert.Equal(t, "SF", s.Accessories)
+
+	require.True(t, s.Update("<Run|WPos:0.000,0.000,0.000|Bf:15,128|Ln:99|F:250>"))
+	assert.Equal(t, []float64{0.5, 0, -1}, s.MPos)
+	assert.Equal(t, 15, s.PlannerBlocks)
+	assert.Equal(t, 128, s.RXBytes)
+	assert.Equal(t, 99, s.Line)
+	assert.Equal(t, 250.0, s.Feed)
+	assert.Empty(t, s.Pins)
+	assert.Equal(t, []int{100, 50, 120}, s.Override)
+
+	// Grbl 0.9 format
+	require.True(t, s.Update("<Idle,MPos:5.000,0.000,0.000,WPos:4.000,0.000,0.000>"))
+	assert.Equal(t, "Idle", s.State)
+	assert.Equal(t, []float64{5, 0, 0}, s.MPos)
+	assert.Equal(t, []float64{4, 0, 0}, s.WPos)
+
+	assert.False(t, s.Update("ok"))
+}
+
+func TestStatus_Update_Truncated(t *testing.T) {
+	for _, tc := range []struct {
+		data  string
+		state string
+		feed  float64
+	}{
+		{"<>", "", 42},
+		{"<Idle|>", "Idle", 42},
+		{"<Idle|FS>", "Idle", 42},
+		{"<Idle|FS:>", "Idle", 0},
+		{"<Idle|FS:100>", "Idle", 100},
+		{"<Idle|F>", "Idle", 42},
+		{"<Idle|Bf>", "Idle", 42},
+		{"<Idle|Bf:15>", "Idle", 42},
+		{"<Idle|Ln>", "Idle", 42},
+		{"<Idle|MPos>", "Idle", 42},
+		{"<Idle|WPos|WCO>", "Idle", 42},
+		{"<Idle|MPos:1.0|WCO:1,2,3>", "Idle", 42},
+		{"<Idle|Ov|Pn|A>", "Idle", 42},
+		{"<Run:|FS>", "Run", 42},
+		{"<Idle,MPos>", "Idle", 42},
+		{"<Idle,MPos:>", "Idle", 42},
+		{"<Idle,WPos:1.0,2.0>", "Idle", 42},
+	} {
+		s := Status{Feed: 42}
+		var ok bool
+		require.NotPanics(t, func() { ok = s.Update(tc.data) }, tc.data)
+		assert.True(t, ok, tc.data)
+		assert.Equal(t, tc.state, s.State, tc.data)
+		assert.Equal(t, tc.feed, s.Feed, tc.data)
+	}
+}
@@ -13,3 +13,40 @@ type Handler interface {
 
 	PollCommand() string
 }
+
+// An Aborter is a Handler that tracks commands that have been written but not yet
+// completed. Abort is called once the port is closed and should return a response
+// for every outstanding command.
+type Aborter interface {
+	Abort(err error) []CommandResponse
+}
+
+// A MetaWriter is a Handler with meta commands that write to the port. If implemented,
+// HandleMetaWrite is called instead of HandleMeta and any returned items are queued
+// as if they had been sent by the client.
+type MetaWriter interface {
+	HandleMetaWrite(cmd string) (resp string, write []QueueItem)
+}
+
+// An Eventer is a Handler that parses structured data (e.g. status reports) from
+// responses. ResponseEvents is called after HandleResponse for every line read.
+type Eventer interface {
+	ResponseEvents(response string) []Event
+}
+
+// An Initializer is a Handler that needs to configure the device before use. Any
+// lines returned by InitCommands are queued when the Buffer is created, ahead of
+// any other data.
+type Initializer interface {
+	InitCommands() []string
+}
+
+// An Event is structured data parsed from the serial port by a Handler.
+type Event struct {
+	// Type identifies the kind of Value (e.g. `Status`).
+	Type  string
+	Value interface{}
+}
+
+// ByteLen is always zero as events do not count against any buffer.
+func (Event) ByteLen() int { return 0 }
@@ -0,0 +1,287 @@
+package marlin
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mastercactapus/yaspjs/buffer"
+)
+
+const (
+	// lineMax matches Marlin's default BUFSIZE.
+	lineMax = 4
+
+	// rxMax is the size of Marlin's default serial RX buffer, less one.
+	rxMax = 127
+)
+
+type Marlin struct {
+	// q holds lines that have been sent and are waiting for an `ok`.
+	q *buffer.Queue
+
+	mx sync.Mutex
+
+	// nextLine is the next line number assigned by WrapInput.
+	nextLine int
+
+	// lastSent is the highest line number written to the port.
+	lastSent int
+
+	// skipOK is set when a `Resend` is read, as Marlin follows each one with an `ok`
+	// that does not acknowledge a line.
+	skipOK bool
+
+	// resendLine is the line number of the pending resend, or zero. Marlin repeats
+	// `Resend: N` for every line that was in flight after the rejected one, these are
+	// ignored until a line numbered N or higher is acknowledged.
+	resendLine int
+
+	version    string
+	lastStatus string
+}
+
+var (
+	_ buffer.Handler     = &Marlin{}
+	_ buffer.Aborter     = &Marlin{}
+	_ buffer.Initializer = &Marlin{}
+)
+
+func NewHandler() buffer.Handler {
+	return &Marlin{
+		q:        buffer.NewQueue(),
+		nextLine: 1,
+	}
+}
+
+// InitCommands resets the line number, as the controller may not have been reset
+// when the port was opened.
+func (m *Marlin) InitCommands() []string { return []string{Frame(0, "M110 N0")} }
+
+// PollCommand is disabled, as line numbers are assigned when queued and must not race
+// with other input. Use `M155` to have Marlin report temperatures automatically.
+func (m *Marlin) PollCommand() string { return "" }
+func (m *Marlin) CheckBuffer(data string) bool {
+	return m.q.Len() < lineMax && m.q.ByteLen()+len(data) <= rxMax
+}
+func (m *Marlin) IsPaused() bool { return false }
+
+func (m *Marlin) HandleMeta(cmd string) string {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	switch cmd {
+	case "*init*":
+		return m.version
+	case "*status*":
+		return m.lastStatus
+	}
+
+	return ""
+}
+
+func (m *Marlin) HandleInput(input buffer.QueueItem) []buffer.CommandResponse {
+	if n, ok := lineNumber(input.Data); ok {
+		m.mx.Lock()
+		if n > m.lastSent {
+			m.lastSent = n
+		}
+		m.mx.Unlock()
+	}
+
+	m.q.Push(input)
+	return nil
+}
+
+func (m *Marlin) Abort(err error) []buffer.CommandResponse {
+	resp := m.reset(err)
+	m.q.Close()
+	return resp
+}
+
+func (m *Marlin) reset(err error) []buffer.CommandResponse {
+	items := m.q.Reset()
+	resp := make([]buffer.CommandResponse, len(items))
+	for i, item := range items {
+		resp[i].QueueItem = item.(buffer.QueueItem)
+		resp[i].Err = err
+	}
+	return resp
+}
+
+func (m *Marlin) FlowConfig() buffer.FlowConfig {
+	return buffer.FlowConfig{
+		InputSplitFunc: ScanInput,
+		WrapInput:      m.wrapInput,
+		// line numbers are assigned when queued, so lines must be written in order
+		DisableDirect: true,
+		IsMeta:        func(cmd string) bool { return strings.HasPrefix(cmd, "*") },
+	}
+}
+
+// wrapInput frames a command as `N<line> <cmd>*<checksum>\n`. Lines already framed (e.g.
+// by InitCommands) end with a newline, which input split into lines never does.
+func (m *Marlin) wrapInput(cmd string) string {
+	if strings.HasSuffix(cmd, "\n") {
+		return cmd
+	}
+
+	m.mx.Lock()
+	n := m.nextLine
+	m.nextLine++
+	m.mx.Unlock()
+
+	return Frame(n, cmd)
+}
+
+// Frame will prefix `cmd` with the line number `n` and append the checksum and a newline.
+func Frame(n int, cmd string) string {
+	line := "N" + strconv.Itoa(n) + " " + cmd
+	return line + "*" + strconv.Itoa(int(Checksum(line))) + "\n"
+}
+
+// Checksum returns the XOR of all bytes in `line`.
+func Checksum(line string) byte {
+	var cs byte
+	for i := 0; i < len(line); i++ {
+		cs ^= line[i]
+	}
+	return cs
+}
+
+func lineNumber(data string) (int, bool) {
+	if !strings.HasPrefix(data, "N") {
+		return 0, false
+	}
+	end := strings.IndexByte(data, ' ')
+	if end == -1 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(data[1:end])
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// parseResend returns the line number requested by `Resend: N` or `rs N`.
+func parseResend(data string) (int, bool) {
+	var rest string
+	switch {
+	case strings.HasPrefix(data, "Resend:"):
+		rest = data[len("Resend:"):]
+	case strings.HasPrefix(data, "rs "):
+		rest = data[len("rs "):]
+	default:
+		return 0, false
+	}
+	rest = strings.TrimPrefix(strings.TrimSpace(rest), "N")
+	n, err := strconv.Atoi(rest)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+func (m *Marlin) HandleResponse(data string) []buffer.CommandResponse {
+	if n, ok := parseResend(data); ok {
+		return m.resend(n)
+	}
+
+	switch {
+	case data == "ok" || strings.HasPrefix(data, "ok "):
+		if strings.Contains(data, "T:") {
+			m.setStatus(data)
+		}
+
+		m.mx.Lock()
+		if m.skipOK {
+			m.skipOK = false
+			m.mx.Unlock()
+			return nil
+		}
+		m.mx.Unlock()
+
+		if m.q.Len() == 0 {
+			return nil
+		}
+		item := m.q.Shift().(buffer.QueueItem)
+		if n, ok := lineNumber(item.Data); ok {
+			m.mx.Lock()
+			if m.resendLine != 0 && n >= m.resendLine {
+				m.resendLine = 0
+			}
+			m.mx.Unlock()
+		}
+		return []buffer.CommandResponse{{
+			QueueItem: item,
+			Done:      true,
+		}}
+	case data == "start":
+		return m.handleStart()
+	case strings.HasPrefix(data, "FIRMWARE_NAME:"):
+		m.mx.Lock()
+		m.version = data
+		m.mx.Unlock()
+	case strings.HasPrefix(data, "T:") || strings.HasPrefix(data, " T:"):
+		m.setStatus(data)
+	case strings.HasPrefix(data, "busy:"), strings.HasPrefix(data, "echo:"), strings.HasPrefix(data, "Error:"):
+		// keepalive and informational output, the matching `ok` or
+		// `Resend` follows separately
+	}
+
+	return nil
+}
+
+func (m *Marlin) setStatus(data string) {
+	m.mx.Lock()
+	m.lastStatus = data
+	m.mx.Unlock()
+}
+
+// resend will pull every in-flight line numbered `n` or higher so it can be written again.
+func (m *Marlin) resend(n int) []buffer.CommandResponse {
+	m.mx.Lock()
+	m.skipOK = true
+	if m.resendLine == n {
+		// already being resent
+		m.mx.Unlock()
+		return nil
+	}
+	m.resendLine = n
+	m.mx.Unlock()
+
+	var resp []buffer.CommandResponse
+	for _, item := range m.q.Reset() {
+		qi := item.(buffer.QueueItem)
+		if num, ok := lineNumber(qi.Data); ok && num < n {
+			m.q.Push(qi)
+			continue
+		}
+		resp = append(resp, buffer.CommandResponse{QueueItem: qi, Resend: true})
+	}
+
+	return resp
+}
+
+// handleStart fails anything in-flight after the controller resets, and re-syncs the
+// line number so that lines already queued will be accepted.
+//
+// The sync line is framed like any other command, and pushed to `q` by HandleInput
+// once written so that its `ok` is not taken for the next line's.
+func (m *Marlin) handleStart() []buffer.CommandResponse {
+	resp := m.reset(errors.New("reset"))
+
+	m.mx.Lock()
+	m.skipOK = false
+	m.resendLine = 0
+	sync := Frame(m.lastSent, fmt.Sprintf("M110 N%d", m.lastSent))
+	m.mx.Unlock()
+
+	return append(resp, buffer.CommandResponse{
+		QueueItem: buffer.QueueItem{Data: sync},
+		Resend:    true,
+	})
+}
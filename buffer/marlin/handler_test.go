@@ -0,0 +1,131 @@
+package marlin
+
+import (
+	"testing"
+
+	"github.com/mastercactapus/yaspjs/buffer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrame(t *testing.T) {
+	assert.Equal(t, "N1 G28*18\n", Frame(1, "G28"))
+	assert.Equal(t, "N3 T0*57\n", Frame(3, "T0"))
+}
+
+func TestParseResend(t *testing.T) {
+	check := func(data string, expN int, expOK bool) {
+		t.Helper()
+		n, ok := parseResend(data)
+		assert.Equal(t, expOK, ok, data)
+		assert.Equal(t, expN, n, data)
+	}
+
+	check("Resend: 12", 12, true)
+	check("Resend:12", 12, true)
+	check("rs N7", 7, true)
+	check("rs 7", 7, true)
+	check("ok", 0, false)
+	check("Resend: foo", 0, false)
+}
+
+// send will frame and write `cmds` as the Buffer would.
+func send(m *Marlin, cmds ...string) {
+	for _, cmd := range cmds {
+		m.HandleInput(buffer.QueueItem{ID: cmd, Data: m.wrapInput(cmd)})
+	}
+}
+
+func TestHandleResponseResend(t *testing.T) {
+	m := NewHandler().(*Marlin)
+	send(m, "G0 X1", "G0 X2", "G0 X3", "G0 X4")
+
+	done := func(data string) []string {
+		t.Helper()
+		var ids []string
+		for _, resp := range m.HandleResponse(data) {
+			assert.True(t, resp.Done, data)
+			ids = append(ids, resp.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"G0 X1"}, done("ok"))
+	assert.Equal(t, []string{"G0 X2"}, done("ok"))
+
+	// line 3 is corrupted, line 4 was already in flight
+	assert.Nil(t, m.HandleResponse("Error:checksum mismatch, Last Line: 2"))
+	resp := m.HandleResponse("Resend: 3")
+	if assert.Len(t, resp, 2) {
+		assert.True(t, resp[0].Resend)
+		assert.Equal(t, Frame(3, "G0 X3"), resp[0].Data)
+		assert.Equal(t, Frame(4, "G0 X4"), resp[1].Data)
+	}
+	assert.Nil(t, m.HandleResponse("ok"))
+
+	// retransmitted by the Buffer
+	for _, r := range resp {
+		m.HandleInput(r.QueueItem)
+	}
+
+	// rejection of the original line 4 must not pull the retransmitted lines again
+	assert.Nil(t, m.HandleResponse("Error:Line Number is not Last Line Number+1, Last Line: 2"))
+	assert.Nil(t, m.HandleResponse("Resend: 3"))
+	assert.Nil(t, m.HandleResponse("ok"))
+	assert.Equal(t, 2, m.q.Len())
+
+	assert.Equal(t, []string{"G0 X3"}, done("ok"))
+	assert.Equal(t, []string{"G0 X4"}, done("ok"))
+	assert.Equal(t, 0, m.q.Len())
+	assert.True(t, m.CheckBuffer(Frame(5, "G0 X5")))
+
+	// once acknowledged, a later resend is handled again
+	send(m, "G0 X5")
+	assert.Len(t, m.HandleResponse("Resend: 5"), 1)
+}
+
+func TestHandleResponseStart(t *testing.T) {
+	m := NewHandler().(*Marlin)
+	send(m, "G28", "G0 X1")
+
+	resp := m.HandleResponse("start")
+	if assert.Len(t, resp, 3) {
+		assert.Error(t, resp[0].Err)
+		assert.Error(t, resp[1].Err)
+		assert.True(t, resp[2].Resend)
+		assert.Equal(t, Frame(2, "M110 N2"), resp[2].Data)
+	}
+
+	// the sync line is written ahead of queued lines
+	m.HandleInput(resp[2].QueueItem)
+	send(m, "G0 X2")
+
+	ok := m.HandleResponse("ok")
+	if assert.Len(t, ok, 1) {
+		assert.Equal(t, resp[2].Data, ok[0].Data)
+	}
+	ok = m.HandleResponse("ok")
+	if assert.Len(t, ok, 1) {
+		assert.Equal(t, "G0 X2", ok[0].ID)
+		assert.Equal(t, Frame(3, "G0 X2"), ok[0].Data)
+	}
+}
+
+func TestInitCommands(t *testing.T) {
+	m := NewHandler().(*Marlin)
+	init := m.InitCommands()
+	if !assert.Len(t, init, 1) {
+		return
+	}
+	assert.Equal(t, "N0 M110 N0*125\n", m.wrapInput(init[0]), "not framed again")
+
+	m.HandleInput(buffer.QueueItem{Data: m.wrapInput(init[0])})
+	send(m, "G28")
+	ok := m.HandleResponse("ok")
+	if assert.Len(t, ok, 1) {
+		assert.Equal(t, init[0], ok[0].Data)
+	}
+	ok = m.HandleResponse("ok")
+	if assert.Len(t, ok, 1) {
+		assert.Equal(t, Frame(1, "G28"), ok[0].Data, "numbering starts after the reset")
+	}
+}
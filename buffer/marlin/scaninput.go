@@ -0,0 +1,21 @@
+package marlin
+
+import (
+	"bufio"
+	"bytes"
+)
+
+// ScanInput will scan Marlin input, stripping out any `;` comments and surrounding whitespace.
+func ScanInput(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	adv, tok, err := bufio.ScanLines(data, atEOF)
+	if len(tok) == 0 {
+		return adv, tok, err
+	}
+
+	start := bytes.IndexByte(tok, ';')
+	if start > -1 {
+		tok = tok[:start]
+	}
+
+	return adv, bytes.TrimSpace(tok), err
+}
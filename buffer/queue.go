@@ -75,12 +75,16 @@ func NewQueue() *Queue {
 		buffer:  make(chan []interface{}),
 
 		condition: make(chan func(interface{}) bool),
+
+		reqClose: make(chan struct{}),
+		close:    make(chan struct{}),
 	}
 	go q.loop()
 	return q
 }
 
 func (q *Queue) loop() {
+	defer close(q.items)
 	defer close(q.len)
 	defer close(q.byteLen)
 	defer close(q.data)
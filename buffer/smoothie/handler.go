@@ -0,0 +1,136 @@
+package smoothie
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mastercactapus/yaspjs/buffer"
+	"github.com/mastercactapus/yaspjs/buffer/grbl"
+)
+
+// smoothieMax is the default send window; Smoothie's serial buffer is much deeper than Grbl's 127 bytes.
+const smoothieMax = 250
+
+// Smoothie handles Smoothieware, which largely follows Grbl's protocol in `grbl_mode`.
+type Smoothie struct {
+	*grbl.Grbl
+
+	// halted is set after `!!` or an alarm, only unlock commands are sent until it is cleared
+	halted  int32
+	version string
+}
+
+var (
+	_ buffer.Handler = &Smoothie{}
+	_ buffer.Aborter = &Smoothie{}
+)
+
+func NewHandler() buffer.Handler { return New(smoothieMax) }
+
+// New will return a Smoothie handler that allows up to `max` bytes to be outstanding.
+func New(max int) *Smoothie {
+	return &Smoothie{Grbl: grbl.New(max)}
+}
+
+func isHalt(cmd string) bool { return strings.TrimSpace(cmd) == "!!" }
+func isUnlock(cmd string) bool {
+	cmd = strings.TrimSpace(cmd)
+	return cmd == "$X" || cmd == "M999"
+}
+
+func (s *Smoothie) isHalted() bool { return atomic.LoadInt32(&s.halted) == 1 }
+
+func (s *Smoothie) IsPaused() bool { return s.isHalted() || s.Grbl.IsPaused() }
+func (s *Smoothie) CheckBuffer(data string) bool {
+	if s.isHalted() && !isUnlock(data) {
+		// anything else would just be rejected with `!!`
+		return false
+	}
+
+	return s.Grbl.CheckBuffer(data)
+}
+
+func (s *Smoothie) FlowConfig() buffer.FlowConfig {
+	cfg := s.Grbl.FlowConfig()
+
+	split := cfg.SplitControlChars
+	cfg.SplitControlChars = func(input string) ([]rune, string) {
+		if isHalt(input) {
+			// `!!` is a halt line, not two feed holds
+			return nil, input
+		}
+		return split(input)
+	}
+
+	// the halt must not wait for room in the window
+	cfg.IsRealtime = isHalt
+
+	isControl := cfg.IsControl
+	cfg.IsControl = func(cmd string) bool { return isUnlock(cmd) || isControl(cmd) }
+
+	isReset := cfg.IsBufferReset
+	cfg.IsBufferReset = func(cmd string) bool { return isHalt(cmd) || isReset(cmd) }
+
+	return cfg
+}
+
+// handleInit reports the Smoothie build version, if known, in place of Grbl's.
+func (s *Smoothie) handleInit(cmd string) (string, bool) {
+	if cmd == "*init*" && s.version != "" {
+		return s.version, true
+	}
+	return "", false
+}
+
+func (s *Smoothie) HandleMeta(cmd string) string {
+	if resp, ok := s.handleInit(cmd); ok {
+		return resp
+	}
+
+	return s.Grbl.HandleMeta(cmd)
+}
+
+func (s *Smoothie) HandleMetaWrite(cmd string) (string, []buffer.QueueItem) {
+	if resp, ok := s.handleInit(cmd); ok {
+		return resp, nil
+	}
+
+	return s.Grbl.HandleMetaWrite(cmd)
+}
+
+func (s *Smoothie) HandleInput(input buffer.QueueItem) []buffer.CommandResponse {
+	if isHalt(input.Data) {
+		atomic.StoreInt32(&s.halted, 1)
+	}
+
+	return s.Grbl.HandleInput(input)
+}
+
+func (s *Smoothie) HandleResponse(data string) []buffer.CommandResponse {
+	if strings.HasPrefix(data, "ok ") {
+		// M105, M114 and friends reply with data on the same line as the `ok`
+		data = "ok"
+	}
+
+	switch {
+	case data == "ok":
+		if buf := s.Grbl.Buffer(); len(buf) > 0 && isUnlock(buf[0].(buffer.QueueItem).Data) {
+			atomic.StoreInt32(&s.halted, 0)
+		}
+	case data == "!!":
+		// any command sent while halted is rejected with `!!`
+		atomic.StoreInt32(&s.halted, 1)
+		data = "error:Halted"
+	case strings.HasPrefix(data, "ALARM"), strings.HasPrefix(data, "error:Alarm lock"):
+		atomic.StoreInt32(&s.halted, 1)
+	case strings.HasPrefix(data, "Build version:"):
+		s.version = data
+	case data == "Smoothie":
+		// printed on startup
+		atomic.StoreInt32(&s.halted, 0)
+		return s.Grbl.Reset(errors.New("reset"))
+	}
+
+	return s.Grbl.HandleResponse(data)
+}
@@ -0,0 +1,137 @@
+package smoothie
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mastercactapus/yaspjs/buffer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSmoothie_Halt(t *testing.T) {
+	s := New(smoothieMax)
+	defer s.Abort(nil)
+
+	assert.True(t, s.CheckBuffer("G0\n"))
+	s.HandleInput(buffer.QueueItem{ID: "a", Data: "G0\n"})
+	s.HandleInput(buffer.QueueItem{Data: "!!\n"})
+	assert.True(t, s.IsPaused())
+	assert.False(t, s.CheckBuffer("G1\n"), "held while halted")
+	assert.True(t, s.CheckBuffer("$X\n"))
+
+	// lines already sent are rejected
+	resp := s.HandleResponse("!!")
+	require.Len(t, resp, 1)
+	assert.Equal(t, "a", resp[0].ID)
+	assert.Error(t, resp[0].Err)
+	s.HandleResponse("!!")
+
+	s.HandleInput(buffer.QueueItem{ID: "b", Data: "$X\n"})
+	assert.True(t, s.IsPaused(), "until unlock is acknowledged")
+	resp = s.HandleResponse("ok")
+	require.Len(t, resp, 1)
+	assert.Equal(t, "b", resp[0].ID)
+	assert.True(t, resp[0].Done)
+	assert.False(t, s.IsPaused())
+	assert.True(t, s.CheckBuffer("G1\n"))
+}
+
+func TestSmoothie_Alarm(t *testing.T) {
+	s := New(smoothieMax)
+	defer s.Abort(nil)
+
+	s.HandleResponse("ALARM: Hard limit")
+	assert.False(t, s.CheckBuffer("G0\n"))
+	assert.True(t, s.CheckBuffer("M999\n"))
+
+	s.HandleResponse("Smoothie")
+	assert.True(t, s.CheckBuffer("G0\n"), "cleared by reset")
+}
+
+func TestSmoothie_Window(t *testing.T) {
+	s := New(10)
+	defer s.Abort(nil)
+
+	s.HandleInput(buffer.QueueItem{Data: "G0 X1\n"})
+	assert.True(t, s.CheckBuffer("G0\n"))
+	assert.False(t, s.CheckBuffer("G0 X2\n"))
+}
+
+func TestSmoothie_Init(t *testing.T) {
+	s := New(smoothieMax)
+	defer s.Abort(nil)
+
+	s.HandleResponse("Build version: edge-94de12c, Build date: Oct 28 2014 13:24:47, MCU: LPC1769, System Clock: 120MHz")
+	exp := "Build version: edge-94de12c, Build date: Oct 28 2014 13:24:47, MCU: LPC1769, System Clock: 120MHz"
+	assert.Equal(t, exp, s.HandleMeta("*init*"))
+	resp, items := s.HandleMetaWrite("*init*")
+	assert.Equal(t, exp, resp)
+	assert.Empty(t, items)
+}
+
+// pipePort is a ReadWriteCloser standing in for a serial port.
+type pipePort struct {
+	*io.PipeReader
+	out *io.PipeWriter
+}
+
+func (p pipePort) Write(b []byte) (int, error) { return p.out.Write(b) }
+func (p pipePort) Close() error {
+	p.PipeReader.Close()
+	return p.out.Close()
+}
+
+func TestSmoothie_HaltWindowFull(t *testing.T) {
+	inR, _ := io.Pipe()
+	outR, outW := io.Pipe()
+	written := make(chan string, 10)
+	go func() {
+		s := bufio.NewScanner(outR)
+		for s.Scan() {
+			written <- s.Text()
+		}
+	}()
+
+	var mx sync.Mutex
+	errs := make(map[string]error)
+	b := buffer.NewBuffer(buffer.Config{
+		ReadWriteCloser: pipePort{PipeReader: inR, out: outW},
+		Handler:         New(8),
+		OnRead:          func(string) {},
+		OnUpdate: func(resp buffer.CommandResponse) {
+			mx.Lock()
+			defer mx.Unlock()
+			if resp.Err != nil {
+				errs[resp.ID] = resp.Err
+			}
+		},
+	})
+	defer b.Close()
+
+	next := func() string {
+		t.Helper()
+		select {
+		case line := <-written:
+			return line
+		case <-time.After(time.Second):
+			t.Fatal("nothing written")
+			return ""
+		}
+	}
+
+	require.NoError(t, b.Queue("a", "G0X1"))
+	assert.Equal(t, "G0X1", next())
+	require.NoError(t, b.Queue("b", "G0X2"))
+	require.NoError(t, b.Queue("", "!!"))
+	assert.Equal(t, "!!", next(), "halt is sent while the window is full")
+
+	assert.Eventually(t, func() bool {
+		mx.Lock()
+		defer mx.Unlock()
+		return errs["b"] == buffer.ErrReset
+	}, time.Second, 10*time.Millisecond, "queued lines are reported as discarded")
+}
@@ -0,0 +1,175 @@
+package tinyg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mastercactapus/yaspjs/buffer"
+)
+
+const (
+	// lineMax is the number of lines that may be sent before waiting for an `r` response,
+	// as recommended by the line-mode protocol.
+	lineMax = 4
+
+	// qrMin is the number of free planner buffers below which new lines will be held.
+	qrMin = 8
+
+	statusOK   = 0
+	statusNoop = 3
+)
+
+type TinyG struct {
+	// q holds lines that have been sent and are waiting for an `r` response.
+	q *buffer.Queue
+
+	mx         sync.Mutex
+	qr         int
+	version    string
+	lastStatus string
+}
+
+var (
+	_ buffer.Handler     = &TinyG{}
+	_ buffer.Aborter     = &TinyG{}
+	_ buffer.Initializer = &TinyG{}
+)
+
+func NewHandler() buffer.Handler {
+	return &TinyG{
+		q:  buffer.NewQueue(),
+		qr: -1,
+	}
+}
+
+// InitCommands switches the controller to JSON mode, as text-mode responses are not
+// tracked, and enables the queue reports used by CheckBuffer.
+func (t *TinyG) InitCommands() []string { return []string{`{"ej":1}`, `{"qv":1}`} }
+
+func (t *TinyG) PollCommand() string { return `{"sr":null}` }
+func (t *TinyG) CheckBuffer(data string) bool {
+	if t.q.Len() >= lineMax {
+		return false
+	}
+
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	return t.qr == -1 || t.qr >= qrMin
+}
+func (t *TinyG) IsPaused() bool { return false }
+
+func (t *TinyG) HandleMeta(cmd string) string {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	switch cmd {
+	case "*init*":
+		return t.version
+	case "*status*":
+		return t.lastStatus
+	}
+
+	return ""
+}
+
+func (t *TinyG) HandleInput(input buffer.QueueItem) []buffer.CommandResponse {
+	if len(input.Data) == 1 {
+		// no control characters expect a response
+		return []buffer.CommandResponse{{QueueItem: input, Done: true}}
+	}
+
+	t.q.Push(input)
+	return nil
+}
+
+func (t *TinyG) Abort(err error) []buffer.CommandResponse {
+	resp := t.reset(err)
+	t.q.Close()
+	return resp
+}
+
+func (t *TinyG) reset(err error) []buffer.CommandResponse {
+	items := t.q.Reset()
+	resp := make([]buffer.CommandResponse, len(items))
+	for i, item := range items {
+		resp[i].QueueItem = item.(buffer.QueueItem)
+		resp[i].Err = err
+	}
+	return resp
+}
+
+func (t *TinyG) FlowConfig() buffer.FlowConfig {
+	return buffer.FlowConfig{
+		SplitControlChars: buffer.SplitStaticControlChars("!~%\x04\x18"),
+		IsControl: func(cmd string) bool {
+			return strings.HasPrefix(cmd, `{"sr"`) || strings.HasPrefix(cmd, `{"qr"`)
+		},
+		IsMeta:        func(cmd string) bool { return strings.HasPrefix(cmd, "*") },
+		IsBufferReset: func(cmd string) bool { return cmd == "\x18" || cmd == "%" || cmd == "\x04" },
+	}
+}
+
+type response struct {
+	R  map[string]json.RawMessage `json:"r"`
+	F  []int                      `json:"f"`
+	SR json.RawMessage            `json:"sr"`
+	QR *int                       `json:"qr"`
+}
+
+func (t *TinyG) HandleResponse(data string) []buffer.CommandResponse {
+	if !strings.HasPrefix(data, "{") {
+		// text-mode output (e.g. `tinyg [mm] ok>`) is not tracked
+		return nil
+	}
+
+	var res response
+	err := json.Unmarshal([]byte(data), &res)
+	if err != nil {
+		return nil
+	}
+
+	t.mx.Lock()
+	if _, ok := res.R["sr"]; ok || res.SR != nil {
+		t.lastStatus = data
+	}
+	if res.QR != nil {
+		t.qr = *res.QR
+	}
+	if qr, ok := res.R["qr"]; ok {
+		json.Unmarshal(qr, &t.qr)
+	}
+	if fb, ok := res.R["fb"]; ok {
+		t.version = string(fb)
+	}
+	t.mx.Unlock()
+
+	if res.R == nil {
+		return nil
+	}
+
+	if msg, ok := res.R["msg"]; ok && strings.Contains(string(msg), "SYSTEM READY") {
+		// startup message after a reset, nothing sent before this will be acknowledged
+		return t.reset(errors.New("reset"))
+	}
+
+	if t.q.Len() == 0 {
+		return nil
+	}
+	item := t.q.Shift().(buffer.QueueItem)
+
+	// footer is [revision, status, rx bytes, checksum]
+	if len(res.F) > 1 && res.F[1] != statusOK && res.F[1] != statusNoop {
+		return []buffer.CommandResponse{{
+			QueueItem: item,
+			Err:       fmt.Errorf("status %d", res.F[1]),
+		}}
+	}
+
+	return []buffer.CommandResponse{{
+		QueueItem: item,
+		Done:      true,
+	}}
+}
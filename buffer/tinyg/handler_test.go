@@ -0,0 +1,85 @@
+package tinyg
+
+import (
+	"testing"
+
+	"github.com/mastercactapus/yaspjs/buffer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTinyG_HandleResponse(t *testing.T) {
+	h := NewHandler().(*TinyG)
+	defer h.q.Close()
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		assert.True(t, h.CheckBuffer("G0\n"))
+		h.HandleInput(buffer.QueueItem{ID: id, Data: "G0\n"})
+	}
+	assert.False(t, h.CheckBuffer("G0\n"), "line limit")
+
+	// text-mode output is ignored
+	assert.Empty(t, h.HandleResponse("tinyg [mm] ok>"))
+
+	resp := h.HandleResponse(`{"r":{"gc":"G0"},"f":[1,0,4,1234]}`)
+	require.Len(t, resp, 1)
+	assert.Equal(t, "a", resp[0].ID)
+	assert.True(t, resp[0].Done)
+
+	resp = h.HandleResponse(`{"r":{},"f":[1,3,4,1234]}`)
+	require.Len(t, resp, 1)
+	assert.Equal(t, "b", resp[0].ID)
+	assert.True(t, resp[0].Done, "noop is not an error")
+
+	resp = h.HandleResponse(`{"r":{"gc":"G0"},"f":[1,100,4,1234]}`)
+	require.Len(t, resp, 1)
+	assert.Equal(t, "c", resp[0].ID)
+	assert.EqualError(t, resp[0].Err, "status 100")
+
+	resp = h.HandleResponse(`{"r":{"msg":"SYSTEM READY"},"f":[1,0,0,0]}`)
+	require.Len(t, resp, 1)
+	assert.Equal(t, "d", resp[0].ID)
+	assert.EqualError(t, resp[0].Err, "reset")
+
+	assert.Empty(t, h.HandleResponse(`{"r":{},"f":[1,0,0,0]}`), "nothing outstanding")
+}
+
+func TestTinyG_QueueReport(t *testing.T) {
+	h := NewHandler().(*TinyG)
+	defer h.q.Close()
+
+	h.HandleResponse(`{"qr":2}`)
+	assert.False(t, h.CheckBuffer("G0\n"))
+	h.HandleResponse(`{"qr":28}`)
+	assert.True(t, h.CheckBuffer("G0\n"))
+
+	h.HandleInput(buffer.QueueItem{Data: `{"qr":null}` + "\n"})
+	h.HandleResponse(`{"r":{"qr":4},"f":[1,0,12,1234]}`)
+	assert.False(t, h.CheckBuffer("G0\n"))
+}
+
+func TestTinyG_Status(t *testing.T) {
+	h := NewHandler().(*TinyG)
+	defer h.q.Close()
+
+	assert.Empty(t, h.HandleMeta("*status*"))
+
+	h.HandleResponse(`{"sr":{"stat":5,"posx":1.0}}`)
+	assert.Equal(t, `{"sr":{"stat":5,"posx":1.0}}`, h.HandleMeta("*status*"))
+
+	h.HandleInput(buffer.QueueItem{Data: `{"sr":null}` + "\n"})
+	resp := h.HandleResponse(`{"r":{"sr":{"stat":3}},"f":[1,0,10,1234]}`)
+	require.Len(t, resp, 1)
+	assert.True(t, resp[0].Done)
+	assert.Equal(t, `{"r":{"sr":{"stat":3}},"f":[1,0,10,1234]}`, h.HandleMeta("*status*"))
+
+	h.HandleResponse(`{"r":{"fb":440.20},"f":[1,0,10,1234]}`)
+	assert.Equal(t, "440.20", h.HandleMeta("*init*"))
+}
+
+func TestTinyG_InitCommands(t *testing.T) {
+	h := NewHandler().(*TinyG)
+	defer h.q.Close()
+
+	assert.Equal(t, []string{`{"ej":1}`, `{"qv":1}`}, h.InitCommands())
+}
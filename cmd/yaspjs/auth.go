@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mastercactapus/yaspjs/server"
+)
+
+// auth checks the origin and token of websocket upgrade requests.
+type auth struct {
+	// origins are the allowed Origin values (scheme://host[:port]), `*` allows any.
+	// Requests without an Origin header (i.e. not from a browser) are always allowed.
+	origins []string
+
+	token         string
+	readOnlyToken string
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+// isLoopback reports if the listen address only accepts local connections.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// CheckOrigin will allow same-host requests, and any origins in the allow-list.
+//
+// The same-host check trusts the Host header, which a page using DNS rebinding controls,
+// so a token should be set whenever the server is reachable beyond the local machine.
+func (a auth) CheckOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, req.Host) {
+		return true
+	}
+
+	for _, allowed := range a.origins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestToken will return the token from the `token` query param or a bearer
+// `Authorization` header.
+func requestToken(req *http.Request) string {
+	if tok := req.URL.Query().Get("token"); tok != "" {
+		return tok
+	}
+
+	const prefix = "Bearer "
+	hdr := req.Header.Get("Authorization")
+	if len(hdr) > len(prefix) && strings.EqualFold(hdr[:len(prefix)], prefix) {
+		return hdr[len(prefix):]
+	}
+	return ""
+}
+
+func tokenEqual(a, b string) bool {
+	return b != "" && subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// Role will return the role for the request's token. If no tokens are configured,
+// all requests have full access.
+func (a auth) Role(req *http.Request) (server.Role, bool) {
+	if a.token == "" && a.readOnlyToken == "" {
+		return server.RoleFull, true
+	}
+
+	tok := requestToken(req)
+	switch {
+	case tokenEqual(tok, a.token):
+		return server.RoleFull, true
+	case tokenEqual(tok, a.readOnlyToken):
+		return server.RoleReadOnly, true
+	}
+	return server.RoleFull, false
+}
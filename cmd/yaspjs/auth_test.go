@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mastercactapus/yaspjs/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuth_CheckOrigin(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		origins []string
+		host    string
+		origin  string
+		ok      bool
+	}{
+		{"no origin (not a browser)", nil, "localhost:8989", "", true},
+		{"same host", nil, "localhost:8989", "http://localhost:8989", true},
+		{"same host, case", nil, "LocalHost:8989", "http://localhost:8989", true},
+		{"same host, other port", nil, "localhost:8989", "http://localhost:3000", false},
+		{"other host", nil, "localhost:8989", "https://evil.example", false},
+		{"invalid origin", nil, "localhost:8989", "://bad", false},
+		{"allowed", []string{"https://chilipeppr.com"}, "localhost:8989", "https://chilipeppr.com", true},
+		{"allowed, trailing slash", []string{"https://chilipeppr.com/"}, "localhost:8989", "https://chilipeppr.com", true},
+		{"allowed, case", []string{"https://ChiliPeppr.com"}, "localhost:8989", "https://chilipeppr.com", true},
+		{"allowed, other scheme", []string{"https://chilipeppr.com"}, "localhost:8989", "http://chilipeppr.com", false},
+		{"not allowed", []string{"https://chilipeppr.com"}, "localhost:8989", "https://evil.example", false},
+		{"any", []string{"*"}, "localhost:8989", "https://evil.example", true},
+	} {
+		req := httptest.NewRequest("GET", "http://"+tc.host+"/ws", nil)
+		if tc.origin != "" {
+			req.Header.Set("Origin", tc.origin)
+		}
+		assert.Equal(t, tc.ok, auth{origins: tc.origins}.CheckOrigin(req), tc.desc)
+	}
+}
+
+func TestAuth_Role(t *testing.T) {
+	full := auth{token: "secret", readOnlyToken: "viewer"}
+	for _, tc := range []struct {
+		desc   string
+		a      auth
+		query  string
+		header string
+		role   server.Role
+		ok     bool
+	}{
+		{"no tokens configured", auth{}, "", "", server.RoleFull, true},
+		{"no tokens configured, any token", auth{}, "?token=foo", "", server.RoleFull, true},
+		{"missing token", full, "", "", server.RoleFull, false},
+		{"query token", full, "?token=secret", "", server.RoleFull, true},
+		{"header token", full, "", "Bearer secret", server.RoleFull, true},
+		{"header token, case", full, "", "bearer secret", server.RoleFull, true},
+		{"header without bearer", full, "", "secret", server.RoleFull, false},
+		{"empty bearer", full, "", "Bearer ", server.RoleFull, false},
+		{"query preferred", full, "?token=viewer", "Bearer secret", server.RoleReadOnly, true},
+		{"read-only token", full, "?token=viewer", "", server.RoleReadOnly, true},
+		{"wrong token", full, "?token=nope", "", server.RoleFull, false},
+		{"read-only only, no token", auth{readOnlyToken: "viewer"}, "", "", server.RoleFull, false},
+		{"read-only only, empty token", auth{readOnlyToken: "viewer"}, "?token=", "", server.RoleFull, false},
+		{"read-only only", auth{readOnlyToken: "viewer"}, "?token=viewer", "", server.RoleReadOnly, true},
+	} {
+		req := httptest.NewRequest("GET", "http://localhost:8989/ws"+tc.query, nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		role, ok := tc.a.Role(req)
+		assert.Equal(t, tc.ok, ok, tc.desc)
+		if ok {
+			assert.Equal(t, tc.role, role, tc.desc)
+		}
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	for addr, exp := range map[string]bool{
+		":8989":          false,
+		"0.0.0.0:8989":   false,
+		"[::]:8989":      false,
+		"192.168.1.2:80": false,
+		"127.0.0.1:8989": true,
+		"[::1]:8989":     true,
+		"localhost:8989": true,
+		"bad":            false,
+	} {
+		assert.Equal(t, exp, isLoopback(addr), addr)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mastercactapus/yaspjs/server"
+	"gopkg.in/yaml.v2"
+)
+
+// fileConfig is the format of the YAML config file. Command-line flags take
+// precedence over values set here.
+type fileConfig struct {
+	Listen string `yaml:"listen"`
+
+	TLS struct {
+		Cert       string `yaml:"cert"`
+		Key        string `yaml:"key"`
+		SelfSigned bool   `yaml:"selfSigned"`
+	} `yaml:"tls"`
+
+	Auth struct {
+		AllowOrigins  []string `yaml:"allowOrigins"`
+		Token         string   `yaml:"token"`
+		ReadOnlyToken string   `yaml:"readOnlyToken"`
+	} `yaml:"auth"`
+
+	Log struct {
+		File       string `yaml:"file"`
+		Timestamps bool   `yaml:"timestamps"`
+	} `yaml:"log"`
+
+	SendQueue       int           `yaml:"sendQueue"`
+	Overflow        string        `yaml:"overflow"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+
+	Ports struct {
+		Include []string `yaml:"include"`
+		Exclude []string `yaml:"exclude"`
+	} `yaml:"ports"`
+
+	DefaultBufferType string        `yaml:"defaultBufferType"`
+	PollInterval      time.Duration `yaml:"pollInterval"`
+	WatchInterval     time.Duration `yaml:"watchInterval"`
+
+	Presets []presetConfig `yaml:"presets"`
+}
+
+// presetConfig is a device preset, identified by `serial` or `path`.
+type presetConfig struct {
+	Serial       string        `yaml:"serial"`
+	Path         string        `yaml:"path"`
+	Alias        string        `yaml:"alias"`
+	Baud         int           `yaml:"baud"`
+	BufferType   string        `yaml:"bufferType"`
+	PollInterval time.Duration `yaml:"pollInterval"`
+	Reconnect    string        `yaml:"reconnect"`
+	DataBits     int           `yaml:"dataBits"`
+	Parity       string        `yaml:"parity"`
+	StopBits     string        `yaml:"stopBits"`
+	FlowControl  string        `yaml:"flowControl"`
+	AutoOpen     bool          `yaml:"autoOpen"`
+}
+
+// defaultConfigPath is used if `-config` is not specified and the file exists.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "yaspjs", "config.yaml")
+}
+
+func loadConfig(path string) (*fileConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg fileConfig
+	err = yaml.UnmarshalStrict(data, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// applyFlags will set any flags that were not given on the command line from the config file.
+func (cfg *fileConfig) applyFlags() error {
+	isSet := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { isSet[f.Name] = true })
+
+	set := func(name, value string) error {
+		if isSet[name] || value == "" {
+			return nil
+		}
+		return flag.Set(name, value)
+	}
+	dur := func(d time.Duration) string {
+		if d == 0 {
+			return ""
+		}
+		return d.String()
+	}
+	num := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.Itoa(n)
+	}
+	boolean := func(b bool) string {
+		if !b {
+			return ""
+		}
+		return "true"
+	}
+
+	for _, f := range []struct{ name, value string }{
+		{"addr", cfg.Listen},
+		{"tls-cert", cfg.TLS.Cert},
+		{"tls-key", cfg.TLS.Key},
+		{"tls-self-signed", boolean(cfg.TLS.SelfSigned)},
+		{"allow-origin", strings.Join(cfg.Auth.AllowOrigins, ",")},
+		{"token", cfg.Auth.Token},
+		{"readonly-token", cfg.Auth.ReadOnlyToken},
+		{"log-file", cfg.Log.File},
+		{"log-timestamps", boolean(cfg.Log.Timestamps)},
+		{"send-queue", num(cfg.SendQueue)},
+		{"overflow", cfg.Overflow},
+		{"shutdown-timeout", dur(cfg.ShutdownTimeout)},
+		{"port-include", strings.Join(cfg.Ports.Include, ",")},
+		{"port-exclude", strings.Join(cfg.Ports.Exclude, ",")},
+		{"default-buffer", cfg.DefaultBufferType},
+		{"poll-interval", dur(cfg.PollInterval)},
+		{"watch-interval", dur(cfg.WatchInterval)},
+	} {
+		err := set(f.name, f.value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+	}
+
+	return nil
+}
+
+// devDir is where device names are checked for conflicts with aliases.
+var devDir = "/dev"
+
+// checkAlias will return an error if the alias could be mistaken for a device name.
+func checkAlias(alias string) error {
+	if strings.ContainsRune(alias, '/') {
+		return fmt.Errorf("alias '%s' must not be a path", alias)
+	}
+	_, err := os.Stat(filepath.Join(devDir, alias))
+	if err == nil {
+		return fmt.Errorf("alias '%s' conflicts with device %s", alias, filepath.Join(devDir, alias))
+	}
+
+	return nil
+}
+
+func (cfg *fileConfig) presets() ([]server.Preset, error) {
+	res := make([]server.Preset, 0, len(cfg.Presets))
+	aliases := make(map[string]bool)
+	for i, p := range cfg.Presets {
+		if p.Serial == "" && p.Path == "" {
+			return nil, fmt.Errorf("preset %d: serial or path is required", i)
+		}
+		if p.Alias != "" {
+			err := checkAlias(p.Alias)
+			if err != nil {
+				return nil, fmt.Errorf("preset %d: %w", i, err)
+			}
+			if aliases[p.Alias] {
+				return nil, fmt.Errorf("preset %d: duplicate alias '%s'", i, p.Alias)
+			}
+			aliases[p.Alias] = true
+		}
+		preset := server.Preset{
+			SerialNumber: p.Serial,
+			Path:         p.Path,
+			Alias:        p.Alias,
+			AutoOpen:     p.AutoOpen,
+		}
+		preset.Baud = p.Baud
+		preset.BufferType = p.BufferType
+		preset.PollInterval = p.PollInterval
+		preset.DataBits = p.DataBits
+		err := preset.PortConfig.SetMode(p.Parity, p.StopBits, p.FlowControl)
+		if err != nil {
+			return nil, fmt.Errorf("preset %d: %w", i, err)
+		}
+		if p.Reconnect != "" {
+			preset.Reconnect, err = server.ParseReconnectPolicy(p.Reconnect)
+			if err != nil {
+				return nil, fmt.Errorf("preset %d: %w", i, err)
+			}
+		}
+		res = append(res, preset)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mastercactapus/yaspjs/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.bug.st/serial"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(data), 0644))
+	return path
+}
+
+func TestLoadConfig_Example(t *testing.T) {
+	cfg, err := loadConfig("../../config.example.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, ":8989", cfg.Listen)
+	assert.Equal(t, 3*time.Second, cfg.PollInterval)
+
+	presets, err := cfg.presets()
+	require.NoError(t, err)
+	require.Len(t, presets, 3)
+
+	assert.Equal(t, "85439313230351F0C1A1", presets[0].SerialNumber)
+	assert.Equal(t, "router-1", presets[0].Alias)
+	assert.Equal(t, "grbl", presets[0].BufferType)
+	assert.Equal(t, 250*time.Millisecond, presets[0].PollInterval)
+	assert.Equal(t, server.ReconnectSerial, presets[0].Reconnect)
+	assert.True(t, presets[0].AutoOpen)
+
+	assert.Equal(t, 7, presets[2].DataBits)
+	assert.Equal(t, serial.EvenParity, presets[2].Parity)
+	assert.Equal(t, serial.OneStopBit, presets[2].StopBits)
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	_, err := loadConfig(writeConfig(t, "listen: :8989\nbogus: true\n"))
+	assert.Error(t, err, "unknown fields are rejected")
+
+	check := func(desc, data string) {
+		t.Helper()
+		cfg, err := loadConfig(writeConfig(t, data))
+		require.NoError(t, err, desc)
+		_, err = cfg.presets()
+		assert.Error(t, err, desc)
+	}
+
+	check("no serial or path", "presets:\n  - alias: foo\n")
+	check("duplicate alias", "presets:\n  - serial: a\n    alias: foo\n  - serial: b\n    alias: foo\n")
+	check("bad reconnect", "presets:\n  - serial: a\n    reconnect: sometimes\n")
+	check("alias is a path", "presets:\n  - serial: a\n    alias: /dev/ttyUSB0\n")
+	check("bad parity", "presets:\n  - serial: a\n    parity: purple\n")
+}
+
+func TestApplyFlags(t *testing.T) {
+	require.NoError(t, flag.CommandLine.Parse([]string{"-addr", ":1234"}))
+
+	cfg, err := loadConfig(writeConfig(t, `
+listen: ":9000"
+overflow: disconnect
+pollInterval: 500ms
+auth:
+  allowOrigins: [https://a.example, https://b.example]
+`))
+	require.NoError(t, err)
+	require.NoError(t, cfg.applyFlags())
+
+	assert.Equal(t, ":1234", *addr, "flags take precedence")
+	assert.Equal(t, "disconnect", *overflow)
+	assert.Equal(t, 500*time.Millisecond, *pollItvl)
+	assert.Equal(t, "https://a.example,https://b.example", *origins)
+	assert.Equal(t, 1024, *sendQueue, "unset values keep the flag default")
+}
+
+func TestCheckAlias(t *testing.T) {
+	orig := devDir
+	defer func() { devDir = orig }()
+	devDir = t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(devDir, "ttyUSB0"), nil, 0644))
+
+	assert.NoError(t, checkAlias("router-1"))
+	assert.EqualError(t, checkAlias("ttyUSB0"), "alias 'ttyUSB0' conflicts with device "+filepath.Join(devDir, "ttyUSB0"))
+	assert.EqualError(t, checkAlias("/dev/ttyUSB0"), "alias '/dev/ttyUSB0' must not be a path")
+}
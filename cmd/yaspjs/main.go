@@ -1,28 +1,133 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/mastercactapus/yaspjs/server"
 )
 
 var (
-	addr = flag.String("addr", ":8989", "HTTP listen address.")
+	configFile = flag.String("config", "", "Path to a YAML config file (default: yaspjs/config.yaml in the user config dir, if it exists).")
+
+	addr      = flag.String("addr", ":8989", "HTTP listen address.")
+	sendQueue = flag.Int("send-queue", 1024, "Max messages queued for each client before applying the overflow policy.")
+	overflow  = flag.String("overflow", "drop-oldest", "Overflow policy for slow clients (drop-oldest, drop-status, or disconnect).")
+	origins   = flag.String("allow-origin", "", "Comma-separated list of origins (e.g. https://chilipeppr.com) allowed in addition to the same host, or * for any.")
+	token     = flag.String("token", "", "If set, clients must provide this token (token query param or bearer Authorization header) for full access.")
+	roToken   = flag.String("readonly-token", "", "If set, clients may provide this token for read-only access.")
+	tlsCert   = flag.String("tls-cert", "", "TLS certificate file. If set (with -tls-key), the server listens with HTTPS/WSS.")
+	tlsKey    = flag.String("tls-key", "", "TLS private key file.")
+	tlsSelf   = flag.Bool("tls-self-signed", false, "Generate a self-signed certificate at -tls-cert/-tls-key (or in the user config dir) on first run and use it.")
+	logFile   = flag.String("log-file", "", "Append logs to this file instead of stderr.")
+	logTime   = flag.Bool("log-timestamps", false, "Include timestamps in log output.")
+	defBuffer = flag.String("default-buffer", "default", "Buffer type used when a port is opened without one.")
+	pollItvl  = flag.Duration("poll-interval", 3*time.Second, "Poll interval used when a port is opened without one.")
+	portIncl  = flag.String("port-include", strings.Join(server.DefaultPortInclude, ","), "Comma-separated device name patterns (e.g. ttyUSB*) or path globs (e.g. /dev/pts/*) to list.")
+	portExcl  = flag.String("port-exclude", "", "Comma-separated device name patterns or paths to hide.")
+	watchItvl = flag.Duration("watch-interval", time.Second, "How often to check for serial devices being added or removed (negative to disable).")
+	shutdown  = flag.Duration("shutdown-timeout", 5*time.Second, "Max time to wait for ports and clients to close on SIGINT or SIGTERM.")
 )
 
 func main() {
 	log.SetFlags(log.Lshortfile)
 	flag.Parse()
 
-	srv := server.NewServer()
+	var err error
+	var presets []server.Preset
+	path := *configFile
+	if path == "" {
+		if _, err := os.Stat(defaultConfigPath()); err == nil {
+			path = defaultConfigPath()
+		}
+	}
+	if path != "" {
+		cfg, err := loadConfig(path)
+		if err != nil {
+			log.Fatalln("ERROR: config:", err)
+		}
+		err = cfg.applyFlags()
+		if err != nil {
+			log.Fatalln("ERROR: config:", err)
+		}
+		presets, err = cfg.presets()
+		if err != nil {
+			log.Fatalln("ERROR: config:", err)
+		}
+	}
+	if *logTime {
+		log.SetFlags(log.LstdFlags | log.Lshortfile)
+	}
+	if *logFile != "" {
+		fd, err := os.OpenFile(*logFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			log.Fatalln("ERROR: open log file:", err)
+		}
+		defer fd.Close()
+		log.SetOutput(fd)
+	}
 
-	// TODO: origin
+	if *tlsSelf {
+		if *tlsCert == "" && *tlsKey == "" {
+			*tlsCert, *tlsKey, err = defaultTLSPaths()
+			if err != nil {
+				log.Fatalln("ERROR: tls:", err)
+			}
+		}
+		err = ensureSelfSigned(*tlsCert, *tlsKey)
+		if err != nil {
+			log.Fatalln("ERROR: tls:", err)
+		}
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		log.Fatalln("ERROR: -tls-cert and -tls-key must be used together")
+	}
+
+	policy, err := server.ParseOverflowPolicy(*overflow)
+	if err != nil {
+		log.Fatalln("ERROR:", err)
+	}
+	srv := server.NewServer(server.Config{
+		SendQueueSize:     *sendQueue,
+		OverflowPolicy:    policy,
+		DefaultBufferType: *defBuffer,
+		PollInterval:      *pollItvl,
+		WatchInterval:     *watchItvl,
+		PortFilter: server.PortFilter{
+			Include: splitList(*portIncl),
+			Exclude: splitList(*portExcl),
+		},
+		Presets: presets,
+	})
+	err = srv.CheckBufferTypes()
+	if err != nil {
+		log.Fatalln("ERROR: config:", err)
+	}
+	srv.AutoOpen()
+
+	a := auth{
+		origins:       splitList(*origins),
+		token:         *token,
+		readOnlyToken: *roToken,
+	}
 	var upgrader websocket.Upgrader
-	upgrader.CheckOrigin = func(req *http.Request) bool { return true }
+	upgrader.CheckOrigin = a.CheckOrigin
 	http.HandleFunc("/ws", func(w http.ResponseWriter, req *http.Request) {
+		role, ok := a.Role(req)
+		if !ok {
+			log.Printf("ERROR: websocket upgrade: invalid token from %s", req.RemoteAddr)
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
 		ws, err := upgrader.Upgrade(w, req, nil)
 		if err != nil {
 			log.Println("ERROR: websocket upgrade:", err)
@@ -30,19 +135,16 @@ func main() {
 		}
 
 		defer ws.Close()
-		conn := srv.NewConn()
+		conn := srv.NewConn(req.Context(), role)
 		defer conn.Close()
 
-		cancel := make(chan struct{})
-		defer close(cancel)
 		go func() {
+			// unblocks ReadMessage once the connection is closed
 			defer ws.Close()
 			for {
 				select {
 				case <-conn.Done():
 					return
-				case <-cancel:
-					return
 				case msg := <-conn.ToClient():
 					err := ws.WriteMessage(websocket.TextMessage, []byte(msg))
 					if err != nil {
@@ -66,8 +168,42 @@ func main() {
 			}
 		}
 	})
-	err := http.ListenAndServe(*addr, nil)
-	if err != nil {
+
+	if *token == "" && *roToken == "" && !isLoopback(*addr) {
+		log.Printf("WARNING: no -token set while listening on %s; any web page a local browser visits may reach the server (e.g. via DNS rebinding). Set -token, or listen on 127.0.0.1.", *addr)
+	}
+
+	httpSrv := &http.Server{Addr: *addr}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	idle := make(chan struct{})
+	go func() {
+		sig := <-sigCh
+		log.Printf("received %v, shutting down", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), *shutdown)
+		defer cancel()
+
+		// stop accepting clients first, websockets are hijacked so they are closed by srv
+		err := httpSrv.Shutdown(ctx)
+		if err != nil {
+			log.Println("ERROR: shutdown http:", err)
+		}
+		err = srv.Shutdown(ctx)
+		if err != nil {
+			log.Println("ERROR: shutdown:", err)
+		}
+		close(idle)
+	}()
+
+	if *tlsCert != "" {
+		log.Printf("listening on %s (TLS)", *addr)
+		err = httpSrv.ListenAndServeTLS(*tlsCert, *tlsKey)
+	} else {
+		log.Printf("listening on %s", *addr)
+		err = httpSrv.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
 		log.Fatalln("ERROR:", err)
 	}
+	<-idle
 }
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureSelfSigned(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	require.NoError(t, ensureSelfSigned(certFile, keyFile))
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+	assert.False(t, cert.IsCA)
+	assert.Contains(t, cert.DNSNames, "localhost")
+
+	// existing pair is kept
+	require.NoError(t, ensureSelfSigned(certFile, keyFile))
+	again, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, pair.Certificate, again.Certificate)
+
+	require.NoError(t, os.Remove(keyFile))
+	assert.Error(t, ensureSelfSigned(certFile, keyFile))
+
+	require.NoError(t, os.Remove(certFile))
+	require.NoError(t, ensureSelfSigned(certFile, keyFile))
+	require.NoError(t, os.Remove(certFile))
+	assert.Error(t, ensureSelfSigned(certFile, keyFile))
+}
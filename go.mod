@@ -4,5 +4,8 @@ go 1.15
 
 require (
 	github.com/gorilla/websocket v1.4.2
+	github.com/stretchr/testify v1.4.0
 	go.bug.st/serial v1.1.1
+	golang.org/x/sys v0.0.0-20200909081042-eff7692f9009
+	gopkg.in/yaml.v2 v2.2.2
 )
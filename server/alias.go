@@ -0,0 +1,70 @@
+package server
+
+import "fmt"
+
+// aliasPreset will return the preset with the given alias, if any.
+func (srv *Server) aliasPreset(alias string) (Preset, bool) {
+	for _, p := range srv.cfg.Presets {
+		if p.Alias != "" && p.Alias == alias {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// portAlias will return the alias of the preset matching the device, if any.
+func (srv *Server) portAlias(name string, info SerialPortInfo) string {
+	for _, p := range srv.cfg.Presets {
+		if p.Alias != "" && p.matches(name, info) {
+			return p.Alias
+		}
+	}
+	return ""
+}
+
+// resolvePort will return the device name for `name`, which may be an alias.
+func (srv *Server) resolvePort(name string) (string, error) {
+	if _, ok := srv.aliasPreset(name); !ok {
+		return name, nil
+	}
+
+	name, _, err := srv.lookupPort(name)
+	return name, err
+}
+
+// lookupPort will return the device name and info for `name`, which may be an alias,
+// listing ports at most once. The info is empty if a device name is not open or listed.
+func (srv *Server) lookupPort(name string) (string, SerialPortInfo, error) {
+	preset, isAlias := srv.aliasPreset(name)
+	match := func(devName string, info SerialPortInfo) bool {
+		if isAlias {
+			return preset.matches(devName, info)
+		}
+		return devName == name
+	}
+
+	// prefer an open port, the device may no longer be listed under the same name
+	ports := <-srv.ports
+	for devName, p := range ports {
+		if match(devName, p.info) {
+			srv.ports <- ports
+			return devName, p.info, nil
+		}
+	}
+	srv.ports <- ports
+
+	list, _, err := srv.listPorts()
+	if err != nil && isAlias {
+		return "", SerialPortInfo{}, fmt.Errorf("resolve alias '%s': %w", name, err)
+	}
+	for _, info := range list {
+		if match(info.Name, info) {
+			return info.Name, info, nil
+		}
+	}
+	if isAlias {
+		return "", SerialPortInfo{}, fmt.Errorf("device for alias '%s' not found", name)
+	}
+
+	return name, SerialPortInfo{}, nil
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stubListPorts(t *testing.T, info []SerialPortInfo, err error) *int {
+	t.Helper()
+	var calls int
+	orig := nativeListPorts
+	nativeListPorts = func(PortFilter) ([]SerialPortInfo, []error, error) {
+		calls++
+		return info, nil, err
+	}
+	t.Cleanup(func() { nativeListPorts = orig })
+	return &calls
+}
+
+func newAliasServer() *Server {
+	srv := &Server{
+		cfg: Config{Presets: []Preset{
+			{SerialNumber: "A123", Alias: "router"},
+			{SerialNumber: "B456", Alias: "printer"},
+		}},
+		ports: make(chan map[string]*Port, 1),
+	}
+	srv.ports <- map[string]*Port{
+		"/dev/ttyUSB0": {name: "/dev/ttyUSB0", info: SerialPortInfo{Name: "/dev/ttyUSB0", SerialNumber: "A123"}},
+	}
+	return srv
+}
+
+func TestServer_ResolvePort(t *testing.T) {
+	calls := stubListPorts(t, []SerialPortInfo{
+		{Name: "/dev/ttyUSB1", SerialNumber: "A123"},
+		{Name: "/dev/ttyACM0", SerialNumber: "B456"},
+	}, nil)
+	srv := newAliasServer()
+
+	name, err := srv.resolvePort("router")
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/ttyUSB0", name, "open port is preferred")
+	assert.Equal(t, 0, *calls)
+
+	name, err = srv.resolvePort("printer")
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/ttyACM0", name)
+	assert.Equal(t, 1, *calls)
+
+	name, err = srv.resolvePort("/dev/ttyS0")
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/ttyS0", name, "not an alias")
+	assert.Equal(t, 1, *calls)
+}
+
+func TestServer_ResolvePort_Missing(t *testing.T) {
+	stubListPorts(t, []SerialPortInfo{{Name: "/dev/ttyUSB1", SerialNumber: "C789"}}, nil)
+	srv := newAliasServer()
+
+	_, err := srv.resolvePort("printer")
+	assert.EqualError(t, err, "device for alias 'printer' not found")
+
+	stubListPorts(t, nil, errors.New("no sysfs"))
+	_, err = srv.resolvePort("printer")
+	assert.EqualError(t, err, "resolve alias 'printer': no sysfs")
+}
+
+func TestServer_LookupPort(t *testing.T) {
+	calls := stubListPorts(t, []SerialPortInfo{{Name: "/dev/ttyACM0", SerialNumber: "B456"}}, nil)
+	srv := newAliasServer()
+
+	name, info, err := srv.lookupPort("/dev/ttyUSB0")
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/ttyUSB0", name)
+	assert.Equal(t, "A123", info.SerialNumber, "from open port")
+
+	name, info, err = srv.lookupPort("printer")
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/ttyACM0", name)
+	assert.Equal(t, "B456", info.SerialNumber)
+
+	name, info, err = srv.lookupPort("/dev/ttyS0")
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/ttyS0", name)
+	assert.Empty(t, info.Name, "not listed")
+	assert.Equal(t, 2, *calls, "listed at most once per lookup")
+}
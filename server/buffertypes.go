@@ -7,11 +7,20 @@ import (
 
 	"github.com/mastercactapus/yaspjs/buffer"
 	"github.com/mastercactapus/yaspjs/buffer/grbl"
+	"github.com/mastercactapus/yaspjs/buffer/marlin"
+	"github.com/mastercactapus/yaspjs/buffer/smoothie"
+	"github.com/mastercactapus/yaspjs/buffer/tinyg"
 )
 
 func (srv *Server) defaultBufferTypes() {
 	srv.RegisterBufferType("default", buffer.NewDefault)
 	srv.RegisterBufferType("grbl", grbl.NewHandler)
+	srv.RegisterBufferType("grblhal", grbl.NewHALHandler)
+	srv.RegisterBufferType("fluidnc", grbl.NewHALHandler)
+	srv.RegisterBufferType("marlin", marlin.NewHandler)
+	srv.RegisterBufferType("smoothie", smoothie.NewHandler)
+	srv.RegisterBufferType("tinyg", tinyg.NewHandler)
+	srv.RegisterBufferType("g2core", tinyg.NewHandler)
 }
 
 func (srv *Server) RegisterBufferType(name string, wrap func() buffer.Handler) error {
@@ -28,3 +37,19 @@ func (srv *Server) RegisterBufferType(name string, wrap func() buffer.Handler) e
 
 	return nil
 }
+
+// CheckBufferTypes will return an error if the default buffer type, or that of any
+// preset, has not been registered. It should be called after any custom buffer types
+// are registered.
+func (srv *Server) CheckBufferTypes() error {
+	if srv.bufferTypeFns[srv.cfg.DefaultBufferType] == nil {
+		return fmt.Errorf("unknown default buffer type '%s'", srv.cfg.DefaultBufferType)
+	}
+	for i, p := range srv.cfg.Presets {
+		if p.BufferType != "" && srv.bufferTypeFns[p.BufferType] == nil {
+			return fmt.Errorf("preset %d: unknown buffer type '%s'", i, p.BufferType)
+		}
+	}
+
+	return nil
+}
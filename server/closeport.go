@@ -0,0 +1,71 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mastercactapus/yaspjs/buffer"
+)
+
+func (srv *Server) handleClosePort(req request, argStr string) {
+	args := strings.Fields(argStr)
+	var res Response
+	if len(args) == 0 {
+		res.Cmd = "CloseFail"
+		res.Desc = "missing port name"
+		req.respondJSON(res)
+		return
+	}
+
+	res.Cmd = "Close"
+	res.Desc = "Got unregister/close on port."
+	var err error
+	res.Port, err = srv.resolvePort(args[0])
+	if err == nil {
+		if res.Port != args[0] {
+			res.Alias = args[0]
+		}
+		res.Baud, err = srv.ClosePort(res.Port)
+	}
+	if err != nil {
+		res.Cmd = "CloseFail"
+		res.Desc = err.Error()
+	}
+	if res.Cmd == "CloseFail" {
+		req.respondJSON(res)
+		return
+	}
+	req.announceJSON(res.Port, classPorts, res)
+}
+
+// ClosePort will close the named port, aborting any pending commands. The baud
+// rate the port was opened with is returned.
+func (srv *Server) ClosePort(name string) (int, error) {
+	ports := <-srv.ports
+	p := ports[name]
+	delete(ports, name)
+	srv.ports <- ports
+
+	if p == nil {
+		if srv.cancelReconnect(name) {
+			return 0, nil
+		}
+		return 0, errors.New("specified port not open")
+	}
+
+	return srv.closePort(p)
+}
+
+func (srv *Server) closePort(p *Port) (int, error) {
+	err := p.Close()
+	if errors.Is(err, buffer.ErrClosed) {
+		// already closed due to an I/O error
+		err = nil
+	}
+	if err != nil {
+		return p.cfg.Baud, fmt.Errorf("close port: %w", err)
+	}
+
+	return p.cfg.Baud, nil
+}
@@ -2,11 +2,13 @@ package server
 
 import (
 	"fmt"
+	"log"
 	"strings"
 )
 
-func (srv *Server) handleCommand(data string) {
-	srv.send <- data
+func (srv *Server) handleCommand(c *Conn, data string) {
+	req, data := parseRequest(c, data)
+	req.reply(data)
 	parts := strings.SplitN(data, " ", 2)
 	cmd := parts[0]
 	var argStr string
@@ -14,30 +16,50 @@ func (srv *Server) handleCommand(data string) {
 		argStr = parts[1]
 	}
 
+	if !c.role.allows(cmd) {
+		req.respondErr(fmt.Errorf("command '%s' not allowed for %s connection", cmd, c.role))
+		return
+	}
+
 	switch cmd {
 	case "list":
-		info, err := srv.ListPorts()
+		info, warnings, err := srv.ListPorts()
 		if err != nil {
-			srv.respondErr(fmt.Errorf("list ports: %w", err))
+			req.respondErr(fmt.Errorf("list ports: %w", err))
 			return
 		}
 		var res Response
 		res.SerialPorts = info
-		srv.respondJSON(res)
+		for _, w := range warnings {
+			log.Println("WARNING: list ports:", w)
+			res.Warnings = append(res.Warnings, w.Error())
+		}
+		req.respondJSON(res)
 	case "open":
-		srv.handleOpenPort(argStr)
+		srv.handleOpenPort(req, argStr)
+	case "close":
+		srv.handleClosePort(req, argStr)
 	case "sendjson":
-		srv.handleSendJSON(argStr)
+		srv.handleSendJSON(req, argStr)
 	case "send":
-		srv.handleSend(argStr)
-	// case "sendnobuf":
-	// case "bufferalgorithms":
-	// case "baudrates":
+		srv.handleSend(req, argStr, false)
+	case "sendnobuf":
+		srv.handleSend(req, argStr, true)
+	case "bufferalgorithms":
+		srv.handleBufferAlgorithms(req)
+	case "baudrates":
+		srv.handleBaudRates(req)
 	case "broadcast":
-		srv.send <- argStr
-	// case "version":
-	// case "hostname":
+		srv.publish(message{data: argStr})
+	case "stats":
+		srv.handleStats(req)
+	case "subscribe":
+		srv.handleSubscribe(req, argStr)
+	case "version":
+		srv.handleVersion(req)
+	case "hostname":
+		srv.handleHostname(req)
 	default:
-		srv.respondErr(fmt.Errorf("unknown command '%s'", cmd))
+		req.respondErr(fmt.Errorf("unknown command '%s'", cmd))
 	}
 }
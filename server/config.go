@@ -0,0 +1,55 @@
+package server
+
+import "time"
+
+// Config contains the options for a Server.
+type Config struct {
+	// SendQueueSize is the number of messages that may be waiting to be sent to each
+	// connection before OverflowPolicy is applied.
+	SendQueueSize int
+
+	OverflowPolicy OverflowPolicy
+
+	// DefaultBufferType is used when a port is opened without one.
+	DefaultBufferType string
+
+	// PollInterval is used when a port is opened without one.
+	PollInterval time.Duration
+
+	// WatchInterval is how often ports are listed to detect devices being added or
+	// removed. A negative value disables watching.
+	WatchInterval time.Duration
+
+	// PortFilter selects which devices are listed. If Include is empty,
+	// DefaultPortInclude is used.
+	PortFilter PortFilter
+
+	// Presets are per-device defaults used when opening a port, and by AutoOpen.
+	Presets []Preset
+}
+
+const (
+	defaultSendQueueSize = 1024
+	defaultPollInterval  = 3 * time.Second
+	defaultWatchInterval = time.Second
+)
+
+func (cfg Config) WithDefaults() Config {
+	if cfg.SendQueueSize <= 0 {
+		cfg.SendQueueSize = defaultSendQueueSize
+	}
+	if cfg.DefaultBufferType == "" {
+		cfg.DefaultBufferType = "default"
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if len(cfg.PortFilter.Include) == 0 {
+		cfg.PortFilter.Include = DefaultPortInclude
+	}
+	if cfg.WatchInterval == 0 {
+		cfg.WatchInterval = defaultWatchInterval
+	}
+
+	return cfg
+}
@@ -1,25 +1,124 @@
 package server
 
-import "sync/atomic"
+import (
+	"context"
+	"sync/atomic"
+)
 
 type Conn struct {
 	id   int32
 	srv  *Server
 	send chan string
+
+	input chan string
+	role  Role
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	out    *outQueue
+	kicked int32
+
+	sub subscription
+}
+
+// connInput is a command from a specific connection.
+type connInput struct {
+	conn *Conn
+	data string
 }
 
-func (srv *Server) NewConn() *Conn {
+// NewConn will register a new client connection, limited to the commands allowed by `role`.
+// The connection is closed when `ctx` is canceled, Close is called, or the server is shut down.
+func (srv *Server) NewConn(ctx context.Context, role Role) *Conn {
+	ctx, cancel := context.WithCancel(ctx)
 	conn := &Conn{
-		id:   atomic.AddInt32(&srv.cid, 1),
-		srv:  srv,
-		send: make(chan string, 1),
+		id:     atomic.AddInt32(&srv.cid, 1),
+		srv:    srv,
+		send:   make(chan string, 1),
+		input:  make(chan string),
+		role:   role,
+		ctx:    ctx,
+		cancel: cancel,
+		out:    newOutQueue(srv.cfg.SendQueueSize, srv.cfg.OverflowPolicy),
 	}
-	srv.newConn <- conn
+
+	conns := <-srv.conns
+	select {
+	case <-srv.closing:
+		// don't accept new connections during shutdown
+		cancel()
+	default:
+		conns = append(conns, conn)
+	}
+	srv.conns <- conns
+
+	go conn.inputLoop()
+	go conn.outputLoop()
+	go func() {
+		<-ctx.Done()
+		srv.removeConn(conn)
+	}()
 
 	return conn
 }
 
-func (c *Conn) FromClient() chan<- string { return c.srv.input }
+func (srv *Server) removeConn(c *Conn) {
+	origConns := <-srv.conns
+	// copy, since others may still be iterating a snapshot of the old slice
+	conns := make([]*Conn, 0, len(origConns))
+	for _, conn := range origConns {
+		if conn == c {
+			continue
+		}
+		conns = append(conns, conn)
+	}
+	srv.conns <- conns
+}
+
+// inputLoop tags commands with the connection they came from.
+func (c *Conn) inputLoop() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case data := <-c.input:
+			select {
+			case <-c.ctx.Done():
+				return
+			case c.srv.input <- connInput{conn: c, data: data}:
+			}
+		}
+	}
+}
+
+// outputLoop feeds queued messages to the client, so that a slow client only blocks itself.
+func (c *Conn) outputLoop() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.out.notify:
+		}
+
+		for _, msg := range c.out.shift() {
+			select {
+			case <-c.ctx.Done():
+				return
+			case c.send <- msg.data:
+			}
+		}
+	}
+}
+
+func (c *Conn) FromClient() chan<- string { return c.input }
 func (c *Conn) ToClient() <-chan string   { return c.send }
-func (c *Conn) Close()                    { c.srv.closeConn <- c.id }
-func (c *Conn) Done() <-chan struct{}     { return c.srv.closeConnsCh }
+
+// Close will close the connection. It never blocks and is safe to call more than once.
+func (c *Conn) Close() { c.cancel() }
+
+// Done is closed once the connection is closed.
+func (c *Conn) Done() <-chan struct{} { return c.ctx.Done() }
+
+// Context is canceled once the connection is closed.
+func (c *Conn) Context() context.Context { return c.ctx }
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// openPTY will return the master fd and the device name of a new pseudo-terminal.
+func openPTY(t *testing.T) (int, string) {
+	t.Helper()
+	master, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		t.Skip("pty unavailable:", err)
+	}
+	t.Cleanup(func() { unix.Close(master) })
+
+	require.NoError(t, unix.IoctlSetPointerInt(master, unix.TIOCSPTLCK, 0))
+	n, err := unix.IoctlGetInt(master, unix.TIOCGPTN)
+	require.NoError(t, err)
+
+	return master, fmt.Sprintf("/dev/pts/%d", n)
+}
+
+func TestSetFlowControl(t *testing.T) {
+	_, name := openPTY(t)
+	fd, err := unix.Open(name, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	require.NoError(t, err)
+	defer unix.Close(fd)
+
+	require.NoError(t, setFlowControl(fd, FlowRTSCTS))
+	tio, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	require.NoError(t, err)
+	assert.NotZero(t, tio.Cflag&unix.CRTSCTS)
+
+	require.NoError(t, setFlowControl(fd, FlowXONXOFF))
+	tio, err = unix.IoctlGetTermios(fd, ioctlGetTermios)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(unix.IXON|unix.IXOFF), tio.Iflag&(unix.IXON|unix.IXOFF))
+}
+
+func TestOpenSerial_FlowControl(t *testing.T) {
+	_, name := openPTY(t)
+
+	sp, err := openSerial(name, PortConfig{Baud: 115200, DataBits: 8, FlowControl: FlowRTSCTS})
+	require.NoError(t, err)
+	defer sp.Close()
+
+	// settings belong to the device, so check them from another descriptor (the
+	// port is exclusive once open, which only root can bypass)
+	fd, err := unix.Open(name, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		t.Skip("cannot reopen exclusive port:", err)
+	}
+	defer unix.Close(fd)
+	tio, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	require.NoError(t, err)
+	assert.NotZero(t, tio.Cflag&unix.CRTSCTS, "set after serial.Open cleared it")
+}
@@ -0,0 +1,21 @@
+//go:build !linux && !darwin && !freebsd && !openbsd
+// +build !linux,!darwin,!freebsd,!openbsd
+
+package server
+
+import (
+	"errors"
+
+	"go.bug.st/serial"
+)
+
+// openSerial will open the port. Flow control is only set through termios on the
+// unix platforms supported by go.bug.st/serial (see flowcontrol_unix.go), so it is
+// rejected here, including on Windows.
+func openSerial(name string, cfg PortConfig) (serial.Port, error) {
+	if cfg.FlowControl != FlowNone {
+		return nil, errors.New("flow control unsupported on this platform")
+	}
+
+	return serial.Open(name, cfg.serialMode())
+}
@@ -0,0 +1,56 @@
+//go:build linux || darwin || freebsd || openbsd
+// +build linux darwin freebsd openbsd
+
+package server
+
+import (
+	"fmt"
+
+	"go.bug.st/serial"
+	"golang.org/x/sys/unix"
+)
+
+// openSerial will open the port, enabling flow control if set.
+//
+// go.bug.st/serial (v1.1.1) has no flow control option, and disables RTS/CTS when
+// opening. Termios settings belong to the device rather than the descriptor, so they
+// are set through a second descriptor opened first, as serial.Open makes the
+// device exclusive.
+func openSerial(name string, cfg PortConfig) (serial.Port, error) {
+	if cfg.FlowControl == FlowNone {
+		return serial.Open(name, cfg.serialMode())
+	}
+
+	fd, err := unix.Open(name, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(fd)
+
+	sp, err := serial.Open(name, cfg.serialMode())
+	if err != nil {
+		return nil, err
+	}
+	err = setFlowControl(fd, cfg.FlowControl)
+	if err != nil {
+		sp.Close()
+		return nil, fmt.Errorf("set flow control: %w", err)
+	}
+
+	return sp, nil
+}
+
+func setFlowControl(fd int, flow FlowControl) error {
+	t, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return err
+	}
+	switch flow {
+	case FlowRTSCTS:
+		t.Cflag |= unix.CRTSCTS
+	case FlowXONXOFF:
+		t.Iflag |= unix.IXON | unix.IXOFF
+	}
+
+	return unix.IoctlSetTermios(fd, ioctlSetTermios, t)
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"fmt"
+	"os"
+)
+
+// Version is reported by the `version` command. It can be set at link time with:
+//
+//	-ldflags "-X github.com/mastercactapus/yaspjs/server.Version=1.2.3"
+var Version = "dev"
+
+// BaudRates are the rates reported by the `baudrates` command.
+var BaudRates = []int{300, 1200, 2400, 4800, 9600, 19200, 38400, 57600, 74880, 115200, 230400, 250000, 500000, 1000000, 2000000}
+
+func (srv *Server) handleBufferAlgorithms(req request) {
+	var res struct {
+		BufferAlgorithm []string
+	}
+	res.BufferAlgorithm = srv.bufferTypeNames
+	req.respondJSON(res)
+}
+
+func (srv *Server) handleBaudRates(req request) {
+	var res struct {
+		BaudRate []int
+	}
+	res.BaudRate = BaudRates
+	req.respondJSON(res)
+}
+
+func (srv *Server) handleVersion(req request) {
+	var res struct {
+		Version string
+	}
+	res.Version = Version
+	req.respondJSON(res)
+}
+
+func (srv *Server) handleHostname(req request) {
+	name, err := os.Hostname()
+	if err != nil {
+		req.respondErr(fmt.Errorf("get hostname: %w", err))
+		return
+	}
+
+	var res struct {
+		Hostname string
+	}
+	res.Hostname = name
+	req.respondJSON(res)
+}
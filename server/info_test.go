@@ -0,0 +1,45 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_InfoCommands(t *testing.T) {
+	srv := newTestServer(t)
+	c := newTestClient(t, srv, RoleReadOnly)
+
+	// each reply is checked in the original SPJS shape
+	var res struct {
+		BufferAlgorithm []string
+		BaudRate        []int
+		Version         string
+		Hostname        string
+	}
+	reply := func(cmd string, field func() bool) {
+		t.Helper()
+		c.send(cmd)
+		c.expectRaw(cmd, func(data string) bool {
+			return json.Unmarshal([]byte(data), &res) == nil && field()
+		})
+	}
+
+	reply("bufferalgorithms", func() bool { return res.BufferAlgorithm != nil })
+	assert.Equal(t, srv.bufferTypeNames, res.BufferAlgorithm)
+	assert.Contains(t, res.BufferAlgorithm, "grbl")
+
+	reply("baudrates", func() bool { return res.BaudRate != nil })
+	assert.Equal(t, BaudRates, res.BaudRate)
+
+	reply("version", func() bool { return res.Version != "" })
+	assert.Equal(t, Version, res.Version)
+
+	reply("hostname", func() bool { return res.Hostname != "" })
+	name, err := os.Hostname()
+	require.NoError(t, err)
+	assert.Equal(t, name, res.Hostname)
+}
@@ -7,6 +7,7 @@ import (
 
 type SerialPortInfo struct {
 	Name         string
+	Alias        string `json:",omitempty"`
 	FriendlyName string `json:"Friendly"`
 	SerialNumber string
 	DeviceClass  string
@@ -15,30 +16,69 @@ type SerialPortInfo struct {
 	Version      float32 `json:"Ver"`
 	RelatedNames []string
 
+	Manufacturer string `json:",omitempty"`
+
+	// Driver is the kernel driver (e.g. `cp210x`, `ftdi_sio`, `cdc_acm`) and Interface the
+	// USB interface number, if known.
+	Driver    string `json:",omitempty"`
+	Interface string `json:",omitempty"`
+
 	IsOpen          bool
 	IsPrimary       bool
 	Baud            int
 	BufferAlgorithm string
 
+	DataBits    int    `json:",omitempty"`
+	Parity      string `json:",omitempty"`
+	StopBits    string `json:",omitempty"`
+	FlowControl string `json:",omitempty"`
+
 	AvailableBufferAlgorithms []string
 }
 
-var nativeListPorts func() ([]SerialPortInfo, error)
+// nativeListPorts returns the ports matching `filter`. If some devices could not
+// be read, the rest are returned along with warnings.
+var nativeListPorts func(filter PortFilter) ([]SerialPortInfo, []error, error)
 
-func (srv *Server) ListPorts() ([]SerialPortInfo, error) {
+// listPorts will return the ports matching the configured filter, without open state.
+func (srv *Server) listPorts() ([]SerialPortInfo, []error, error) {
 	if nativeListPorts == nil {
-		return nil, errors.New("unsupported on this platform")
+		return nil, nil, errors.New("unsupported on this platform")
 	}
 
-	info, err := nativeListPorts()
+	info, warnings, err := nativeListPorts(srv.cfg.PortFilter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[string]bool, len(info))
+	for _, i := range info {
+		seen[i.Name] = true
+	}
+	for _, i := range srv.cfg.PortFilter.globPorts() {
+		if seen[i.Name] {
+			continue
+		}
+		seen[i.Name] = true
+		info = append(info, i)
+	}
+
+	return info, warnings, nil
+}
+
+// ListPorts will return all matching ports, including open state. If some devices
+// could not be read, the rest are returned along with warnings.
+func (srv *Server) ListPorts() ([]SerialPortInfo, []error, error) {
+	info, warnings, err := srv.listPorts()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	sort.Slice(info, func(i, j int) bool { return info[i].Name < info[j].Name })
 
 	ports := <-srv.ports
 	for i := range info {
 		info[i].AvailableBufferAlgorithms = srv.bufferTypeNames
+		info[i].Alias = srv.portAlias(info[i].Name, info[i])
 		p := ports[info[i].Name]
 		if p == nil {
 			continue
@@ -46,10 +86,14 @@ func (srv *Server) ListPorts() ([]SerialPortInfo, error) {
 
 		info[i].IsOpen = true
 		info[i].IsPrimary = p.primary
-		info[i].Baud = p.baudRate
-		info[i].BufferAlgorithm = p.bufferType
+		info[i].Baud = p.cfg.Baud
+		info[i].BufferAlgorithm = p.cfg.BufferType
+		info[i].DataBits = p.cfg.DataBits
+		info[i].Parity = parityString(p.cfg.Parity)
+		info[i].StopBits = stopBitsString(p.cfg.StopBits)
+		info[i].FlowControl = p.cfg.FlowControl.String()
 	}
 	srv.ports <- ports
 
-	return info, nil
+	return info, warnings, nil
 }
@@ -1,8 +1,8 @@
 package server
 
 import (
+	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -10,82 +10,185 @@ import (
 )
 
 func init() {
-	nativeListPorts = linuxListPorts
+	nativeListPorts = sysfs{root: "/sys", dev: "/dev"}.listPorts
 }
 
-func linuxListPorts() ([]SerialPortInfo, error) {
-	var devicePaths []string
-	err := filepath.Walk("/sys/devices", func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			log.Fatal(err)
+// sysfs lists ports from a sysfs tree, `root` and `dev` are only changed for testing.
+type sysfs struct {
+	root string
+	dev  string
+}
+
+// serialLinkDirs contain stable symlinks to serial devices, reported in RelatedNames.
+var serialLinkDirs = []string{"serial/by-id", "serial/by-path"}
+
+// listPorts will list tty devices from the `class/tty` index. Devices that vanish or
+// can't be read while listing are skipped and reported as warnings.
+func (fs sysfs) listPorts(filter PortFilter) ([]SerialPortInfo, []error, error) {
+	classDir := filepath.Join(fs.root, "class", "tty")
+	entries, err := ioutil.ReadDir(classDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read tty class: %w", err)
+	}
+	devicesDir, err := filepath.EvalSymlinks(filepath.Join(fs.root, "devices"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("read devices: %w", err)
+	}
+
+	var warnings []error
+	info := make([]SerialPortInfo, 0, len(entries))
+	usbPaths := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !filter.Matches("/dev/" + e.Name()) {
+			continue
 		}
 
-		if !info.IsDir() {
-			return nil
+		// entries are links to the class device (e.g. `.../1-1:1.0/tty/ttyACM0`)
+		path, err := filepath.EvalSymlinks(filepath.Join(classDir, e.Name()))
+		if err != nil {
+			warnings = append(warnings, fmt.Errorf("%s: %w", e.Name(), err))
+			continue
 		}
-		base := filepath.Base(path)
-		if strings.HasPrefix(base, "ttyA") || strings.HasPrefix(base, "ttyU") {
-			devicePaths = append(devicePaths, path)
+		if isUnknownUART(path) {
+			continue
 		}
 
-		return nil
-	})
-	if err != nil {
-		return nil, err
+		i, usbPath, err := sysfsPortInfo(devicesDir, path)
+		if err != nil {
+			warnings = append(warnings, fmt.Errorf("%s: %w", e.Name(), err))
+			continue
+		}
+		info = append(info, i)
+		usbPaths = append(usbPaths, usbPath)
 	}
 
-	info := make([]SerialPortInfo, 0, len(devicePaths))
-	for _, path := range devicePaths {
-		portName := filepath.Base(path)
-		for path != "/" {
-			path = filepath.Dir(path)
+	links, err := fs.serialLinks()
+	if err != nil {
+		warnings = append(warnings, err)
+	}
 
-			data := func(name string) []byte {
-				data, _ := ioutil.ReadFile(filepath.Join(path, name))
-				return data
-			}
-			str := func(name string) string { return strings.TrimSpace(string(data(name))) }
-			float := func(name string) float32 {
-				f, _ := strconv.ParseFloat(str(name), 32)
-				return float32(f)
+	for n := range info {
+		// other ports on the same USB device (e.g. dual-channel adapters)
+		for m := range info {
+			if m == n || usbPaths[n] == "" || usbPaths[m] != usbPaths[n] {
+				continue
 			}
+			info[n].RelatedNames = append(info[n].RelatedNames, info[m].Name)
+		}
+		info[n].RelatedNames = append(info[n].RelatedNames, links[info[n].Name]...)
+	}
 
-			_, err := os.Stat(filepath.Join(path, "product"))
-			if os.IsNotExist(err) {
-				_, err = os.Stat(filepath.Join(path, "manufacturer"))
-			}
-			if os.IsNotExist(err) {
+	return info, warnings, nil
+}
+
+// isUnknownUART will return true for serial-core ports with no hardware present,
+// e.g. the `ttyS*` ports that are always registered on x86.
+func isUnknownUART(path string) bool {
+	data, err := ioutil.ReadFile(filepath.Join(path, "type"))
+	return err == nil && strings.TrimSpace(string(data)) == "0"
+}
+
+// serialLinks will return a map of device names (e.g. `/dev/ttyUSB0`) to the stable
+// symlinks that point to them. Missing link dirs are not an error, they are only
+// created by udev once a matching device is present.
+func (fs sysfs) serialLinks() (map[string][]string, error) {
+	links := make(map[string][]string)
+	devDir, err := filepath.EvalSymlinks(fs.dev)
+	if err != nil {
+		return links, fmt.Errorf("read %s: %w", fs.dev, err)
+	}
+
+	for _, dir := range serialLinkDirs {
+		entries, err := ioutil.ReadDir(filepath.Join(fs.dev, dir))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return links, fmt.Errorf("read %s: %w", dir, err)
+		}
+		for _, e := range entries {
+			target, err := filepath.EvalSymlinks(filepath.Join(fs.dev, dir, e.Name()))
+			if err != nil {
+				// device removed while listing
 				continue
 			}
+			rel, err := filepath.Rel(devDir, target)
 			if err != nil {
-				return nil, err
+				continue
 			}
+			name := "/dev/" + filepath.ToSlash(rel)
+			links[name] = append(links[name], filepath.Join("/dev", dir, e.Name()))
+		}
+	}
+	return links, nil
+}
 
-			var related []string
-			for _, relPath := range devicePaths {
-				if !strings.HasPrefix(relPath, path) {
-					continue
-				}
-				base := filepath.Base(relPath)
-				if base == portName {
-					continue
-				}
-				related = append(related, "/dev/"+base)
-			}
+// sysfsPortInfo will return the info for the tty class device at `path`, and the sysfs
+// path of the USB device it belongs to, if any.
+func sysfsPortInfo(devicesDir, path string) (SerialPortInfo, string, error) {
+	info := SerialPortInfo{Name: "/dev/" + filepath.Base(path)}
+
+	// `device` links to the hardware (e.g. the USB interface or usb-serial port)
+	dev, err := filepath.EvalSymlinks(filepath.Join(path, "device"))
+	if os.IsNotExist(err) {
+		// virtual device (e.g. rfcomm or pty)
+		return info, "", nil
+	}
+	if err != nil {
+		return info, "", err
+	}
+	info.Driver = deviceDriver(devicesDir, dev)
 
-			info = append(info, SerialPortInfo{
-				Name:         "/dev/" + portName,
-				RelatedNames: related,
-				DeviceClass:  str("bDeviceClass"),
-				FriendlyName: str("product"),
-				VendorID:     str("idVendor"),
-				ProductID:    str("idProduct"),
-				Version:      float("version"),
-				SerialNumber: str("serial"),
-			})
-			break
+	for dir := dev; strings.HasPrefix(dir, devicesDir+"/"); dir = filepath.Dir(dir) {
+		data := func(name string) []byte {
+			data, _ := ioutil.ReadFile(filepath.Join(dir, name))
+			return data
 		}
+		str := func(name string) string { return strings.TrimSpace(string(data(name))) }
+		float := func(name string) float32 {
+			f, _ := strconv.ParseFloat(str(name), 32)
+			return float32(f)
+		}
+
+		if info.Interface == "" {
+			info.Interface = str("bInterfaceNumber")
+		}
+
+		_, err := os.Stat(filepath.Join(dir, "product"))
+		if os.IsNotExist(err) {
+			_, err = os.Stat(filepath.Join(dir, "manufacturer"))
+		}
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return info, "", err
+		}
+
+		info.DeviceClass = str("bDeviceClass")
+		info.FriendlyName = str("product")
+		info.Manufacturer = str("manufacturer")
+		info.VendorID = str("idVendor")
+		info.ProductID = str("idProduct")
+		info.Version = float("version")
+		info.SerialNumber = str("serial")
+		return info, dir, nil
 	}
 
-	return info, nil
+	return info, "", nil
+}
+
+// deviceDriver will return the name of the driver bound to `dev`. Serial-core
+// ports (e.g. `ttyS*`) are bound to the generic `serial-base` drivers, in that case
+// the nearest ancestor's driver is used (e.g. `serial8250`).
+func deviceDriver(devicesDir, dev string) string {
+	for dir := dev; strings.HasPrefix(dir, devicesDir+"/"); dir = filepath.Dir(dir) {
+		driver, err := filepath.EvalSymlinks(filepath.Join(dir, "driver"))
+		// i.e. /sys/bus/serial-base/drivers/port
+		if err != nil || filepath.Base(filepath.Dir(filepath.Dir(driver))) == "serial-base" {
+			continue
+		}
+		return filepath.Base(driver)
+	}
+	return ""
 }
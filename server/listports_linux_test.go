@@ -0,0 +1,187 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSysfs builds a sysfs and dev tree in a temp dir.
+type fakeSysfs struct {
+	t    *testing.T
+	root string
+}
+
+func (f fakeSysfs) file(path, data string) {
+	path = filepath.Join(f.root, path)
+	require.NoError(f.t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(f.t, ioutil.WriteFile(path, []byte(data+"\n"), 0644))
+}
+
+func (f fakeSysfs) dir(path string) {
+	require.NoError(f.t, os.MkdirAll(filepath.Join(f.root, path), 0755))
+}
+
+// link creates a symlink at `path` to `target`, both relative to the root.
+func (f fakeSysfs) link(path, target string) {
+	path = filepath.Join(f.root, path)
+	require.NoError(f.t, os.MkdirAll(filepath.Dir(path), 0755))
+	rel, err := filepath.Rel(filepath.Dir(path), filepath.Join(f.root, target))
+	require.NoError(f.t, err)
+	require.NoError(f.t, os.Symlink(rel, path))
+}
+
+// tty registers a class device at `path`.
+func (f fakeSysfs) tty(path string) {
+	f.file(filepath.Join(path, "dev"), "188:0")
+	f.link(filepath.Join("sys/class/tty", filepath.Base(path)), path)
+	f.file(filepath.Join("dev", filepath.Base(path)), "")
+}
+
+func newFakeSysfs(t *testing.T) fakeSysfs {
+	dir, err := ioutil.TempDir("", "sysfs")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	f := fakeSysfs{t: t, root: dir}
+
+	// dual-channel FTDI adapter
+	const ftdi = "sys/devices/pci0000:00/0000:00:14.0/usb1/1-1"
+	f.file(ftdi+"/idVendor", "0403")
+	f.file(ftdi+"/idProduct", "6010")
+	f.file(ftdi+"/product", "Dual RS232")
+	f.file(ftdi+"/manufacturer", "FTDI")
+	f.file(ftdi+"/serial", "FT1234")
+	f.file(ftdi+"/bDeviceClass", "00")
+	f.file(ftdi+"/version", " 2.00")
+	f.dir("sys/bus/usb-serial/drivers/ftdi_sio")
+	for i, name := range []string{"ttyUSB0", "ttyUSB1"} {
+		iface := ftdi + "/1-1:1." + string(rune('0'+i))
+		f.file(iface+"/bInterfaceNumber", "0"+string(rune('0'+i)))
+		f.link(iface+"/"+name+"/driver", "sys/bus/usb-serial/drivers/ftdi_sio")
+		f.link(iface+"/"+name+"/tty/"+name+"/device", iface+"/"+name)
+		f.tty(iface + "/" + name + "/tty/" + name)
+	}
+	f.link("dev/serial/by-id/usb-FTDI_Dual_RS232_FT1234-if00-port0", "dev/ttyUSB0")
+
+	// CDC-ACM device (e.g. an Arduino)
+	const acm = "sys/devices/pci0000:00/0000:00:14.0/usb1/1-2"
+	f.file(acm+"/idVendor", "2341")
+	f.file(acm+"/idProduct", "0043")
+	f.file(acm+"/manufacturer", "Arduino")
+	f.file(acm+"/serial", "A5678")
+	f.file(acm+"/1-2:1.0/bInterfaceNumber", "00")
+	f.dir("sys/bus/usb/drivers/cdc_acm")
+	f.link(acm+"/1-2:1.0/driver", "sys/bus/usb/drivers/cdc_acm")
+	f.link(acm+"/1-2:1.0/tty/ttyACM0/device", acm+"/1-2:1.0")
+	f.tty(acm + "/1-2:1.0/tty/ttyACM0")
+
+	// on-board UART, and a placeholder with no hardware
+	const uart = "sys/devices/platform/soc/fe201000.serial"
+	f.dir("sys/bus/amba/drivers/uart-pl011")
+	f.link(uart+"/driver", "sys/bus/amba/drivers/uart-pl011")
+	f.link(uart+"/tty/ttyAMA0/device", uart)
+	f.tty(uart + "/tty/ttyAMA0")
+	f.file("sys/devices/platform/serial8250/tty/ttyS0/type", "0")
+	f.tty("sys/devices/platform/serial8250/tty/ttyS0")
+
+	// virtual devices
+	f.tty("sys/devices/virtual/tty/rfcomm0")
+	f.tty("sys/devices/virtual/tty/tty0")
+
+	return f
+}
+
+func (f fakeSysfs) listPorts(filter PortFilter) ([]SerialPortInfo, []error, error) {
+	return sysfs{root: filepath.Join(f.root, "sys"), dev: filepath.Join(f.root, "dev")}.listPorts(filter)
+}
+
+func TestSysfsListPorts(t *testing.T) {
+	f := newFakeSysfs(t)
+
+	info, warnings, err := f.listPorts(PortFilter{Include: DefaultPortInclude})
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	assert.Equal(t, []SerialPortInfo{
+		{
+			Name: "/dev/rfcomm0",
+		},
+		{
+			Name:      "/dev/ttyACM0",
+			Driver:    "cdc_acm",
+			Interface: "00",
+
+			Manufacturer: "Arduino",
+			VendorID:     "2341",
+			ProductID:    "0043",
+			SerialNumber: "A5678",
+		},
+		{
+			Name:   "/dev/ttyAMA0",
+			Driver: "uart-pl011",
+		},
+		{
+			Name:         "/dev/ttyUSB0",
+			Driver:       "ftdi_sio",
+			Interface:    "00",
+			RelatedNames: []string{"/dev/ttyUSB1", "/dev/serial/by-id/usb-FTDI_Dual_RS232_FT1234-if00-port0"},
+
+			FriendlyName: "Dual RS232",
+			Manufacturer: "FTDI",
+			VendorID:     "0403",
+			ProductID:    "6010",
+			SerialNumber: "FT1234",
+			DeviceClass:  "00",
+			Version:      2,
+		},
+		{
+			Name:         "/dev/ttyUSB1",
+			Driver:       "ftdi_sio",
+			Interface:    "01",
+			RelatedNames: []string{"/dev/ttyUSB0"},
+
+			FriendlyName: "Dual RS232",
+			Manufacturer: "FTDI",
+			VendorID:     "0403",
+			ProductID:    "6010",
+			SerialNumber: "FT1234",
+			DeviceClass:  "00",
+			Version:      2,
+		},
+	}, info)
+}
+
+func TestSysfsListPortsFilter(t *testing.T) {
+	f := newFakeSysfs(t)
+
+	info, _, err := f.listPorts(PortFilter{Include: []string{"ttyUSB*"}, Exclude: []string{"ttyUSB1"}})
+	require.NoError(t, err)
+	require.Len(t, info, 1)
+	assert.Equal(t, "/dev/ttyUSB0", info[0].Name)
+}
+
+func TestSysfsListPortsPartial(t *testing.T) {
+	f := newFakeSysfs(t)
+
+	// device removed mid-listing leaves a dangling class link
+	require.NoError(t, os.RemoveAll(filepath.Join(f.root, "sys/devices/pci0000:00/0000:00:14.0/usb1/1-2")))
+
+	info, warnings, err := f.listPorts(PortFilter{Include: []string{"tty*"}})
+	require.NoError(t, err)
+	assert.Len(t, warnings, 1)
+
+	var names []string
+	for _, i := range info {
+		names = append(names, i.Name)
+	}
+	assert.Equal(t, []string{"/dev/tty0", "/dev/ttyAMA0", "/dev/ttyUSB0", "/dev/ttyUSB1"}, names)
+}
+
+func TestSysfsListPortsMissing(t *testing.T) {
+	_, _, err := sysfs{root: "/nonexistent", dev: "/nonexistent"}.listPorts(PortFilter{Include: DefaultPortInclude})
+	assert.Error(t, err)
+}
@@ -12,111 +12,242 @@ import (
 	"go.bug.st/serial"
 )
 
-func (srv *Server) handleOpenPort(argStr string) {
-	var err error
-	args := strings.Fields(argStr)
-	var res Response
-	switch len(args) {
-	case 0:
-		res.Cmd = "OpenFail"
-		res.Desc = "missing port name"
-	case 1:
+// PortConfig contains the options used when opening a port.
+type PortConfig struct {
+	Baud       int
+	BufferType string
+
+	// PollInterval is how often the buffer's poll command (e.g. `?`) is sent. If
+	// zero, the server default is used. A negative value disables polling.
+	PollInterval time.Duration
+
+	// Reconnect controls what happens if the port is lost due to an I/O error.
+	Reconnect ReconnectPolicy
+
+	// DataBits defaults to 8 if unset. Parity, StopBits, and FlowControl default to 8N1
+	// with no flow control.
+	DataBits    int
+	Parity      serial.Parity
+	StopBits    serial.StopBits
+	FlowControl FlowControl
+}
+
+// handleOpenPort will open a port:
+//
+//	open <port> [baud] [buftype] [off|retry|serial] [option=value ...]
+//
+// Omitted values are taken from a matching preset, then the server defaults. See
+// PortConfig.setOption for the serial mode options (e.g. `mode=7E1 flow=rtscts`).
+func (srv *Server) handleOpenPort(req request, argStr string) {
+	res, err := srv.openFromArgs(strings.Fields(argStr))
+	if err != nil {
 		res.Cmd = "OpenFail"
-		res.Desc = "missing baud rate"
-	case 2:
-		args = append(args, "default")
-		fallthrough
-	case 3:
-		res.Cmd = "Open"
-		res.Desc = "Got register/open on port."
+		res.Desc = err.Error()
+		req.respondJSON(res)
+		return
+	}
+	req.announceJSON(res.Port, classPorts, res)
+}
+
+func (srv *Server) openFromArgs(fields []string) (Response, error) {
+	var res Response
+	var args, opts []string
+	for _, f := range fields {
+		if len(args) > 0 && strings.Contains(f, "=") {
+			opts = append(opts, f)
+			continue
+		}
+		args = append(args, f)
+	}
+
+	switch {
+	case len(args) == 0:
+		return res, errors.New("missing port name")
+	case len(args) > 4:
+		return res, errors.New("too many arguments")
+	}
+	var err error
+	var info SerialPortInfo
+	res.Port, info, err = srv.lookupPort(args[0])
+	if err != nil {
 		res.Port = args[0]
-		res.Baud, err = strconv.Atoi(args[1])
+		return res, err
+	}
+
+	// the serial number may be unavailable, but presets can still match by path
+	res.Alias = srv.portAlias(res.Port, info)
+	preset, _ := srv.preset(res.Port, info)
+	cfg := preset.PortConfig
+	if len(args) > 1 {
+		cfg.Baud, err = strconv.Atoi(args[1])
+		if err != nil {
+			return res, fmt.Errorf("invalid baud rate: %w", err)
+		}
+	}
+	if len(args) > 2 {
+		cfg.BufferType = args[2]
+	}
+	if len(args) > 3 {
+		cfg.Reconnect, err = ParseReconnectPolicy(args[3])
 		if err != nil {
-			res.Cmd = "OpenFail"
-			res.Desc = fmt.Sprintf("invalid baud rate: %v", err)
-			break
+			return res, err
 		}
-		res.IsPrimary, err = srv.OpenPort(res.Port, res.Baud, args[2])
+	}
+	for _, opt := range opts {
+		parts := strings.SplitN(opt, "=", 2)
+		err = cfg.setOption(parts[0], parts[1])
 		if err != nil {
-			res.Cmd = "OpenFail"
-			res.Desc = err.Error()
+			return res, err
 		}
 	}
-	srv.respondJSON(res)
+	cfg = srv.withPortDefaults(cfg)
+	res = openResponse(res.Port, res.Alias, cfg)
+
+	res.IsPrimary, err = srv.openPortInfo(res.Port, cfg, info)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
 }
 
-func (srv *Server) OpenPort(name string, baud int, bufferType string) (bool, error) {
-	if baud == 0 {
+// openResponse returns the Open response for a port opened with `cfg`, after defaults
+// are applied.
+func openResponse(name, alias string, cfg PortConfig) Response {
+	res := Response{
+		Cmd:        "Open",
+		Desc:       "Got register/open on port.",
+		Port:       name,
+		Alias:      alias,
+		Baud:       cfg.Baud,
+		BufferType: cfg.BufferType,
+	}
+	cfg.describeMode(&res)
+	return res
+}
+
+func (srv *Server) OpenPort(name string, cfg PortConfig) (bool, error) {
+	// kept to report the alias, and find the device again when reconnecting
+	info, err := srv.portInfo(name)
+	if err != nil && cfg.Reconnect == ReconnectSerial {
+		return false, fmt.Errorf("lookup port: %w", err)
+	}
+
+	return srv.openPortInfo(name, cfg, info)
+}
+
+// openPortInfo works like OpenPort, using the already known info for the device.
+func (srv *Server) openPortInfo(name string, cfg PortConfig, info SerialPortInfo) (bool, error) {
+	cfg = srv.withPortDefaults(cfg)
+	if cfg.Baud == 0 {
 		return false, errors.New("missing baud rate")
 	}
-	newBuf := srv.bufferTypeFns[bufferType]
-	if newBuf == nil {
-		return false, fmt.Errorf("unknown/unsupported buffer type '%s'", bufferType)
+	if srv.bufferTypeFns[cfg.BufferType] == nil {
+		return false, fmt.Errorf("unknown/unsupported buffer type '%s'", cfg.BufferType)
+	}
+	if cfg.Reconnect == ReconnectSerial && info.SerialNumber == "" {
+		return false, errors.New("port does not report a serial number")
 	}
 
+	// an explicit open replaces any pending reconnect
+	srv.cancelReconnect(name)
+
+	p, err := srv.openPort(name, cfg, info)
+	if err != nil {
+		return false, err
+	}
+
+	return p.primary, nil
+}
+
+func (srv *Server) openPort(name string, cfg PortConfig, info SerialPortInfo) (*Port, error) {
 	ports := <-srv.ports
 	p := ports[name]
 	if p != nil {
 		srv.ports <- ports
 
 		// already open
-		return p.primary, nil
+		return p, nil
+	}
+	select {
+	case <-srv.closing:
+		// Shutdown closes all ports after closing, under the same lock
+		srv.ports <- ports
+		return nil, errors.New("server shutting down")
+	default:
 	}
 
-	sp, err := serial.Open(name, &serial.Mode{BaudRate: baud})
+	sp, err := openSerial(name, cfg)
 	if err != nil {
 		srv.ports <- ports
-		return false, fmt.Errorf("open port: %w", err)
+		return nil, fmt.Errorf("open port: %w", err)
 	}
-	primary := len(ports) == 0
 	p = &Port{
-		name:       name,
-		baudRate:   baud,
-		bufferType: bufferType,
-		primary:    primary,
-		Buffer: buffer.NewBuffer(buffer.Config{
-			PollInterval:    3 * time.Second,
-			ReadWriteCloser: sp,
-			Handler:         newBuf(),
-			OnRead: func(line string) {
-				srv.respondJSON(Response{
-					P: name,
-					D: line,
-				})
-			},
-			OnUpdate: func(cmd buffer.CommandResponse) {
-				if cmd.ID == "" {
-					return
-				}
-				res := Response{
-					P:    name,
-					QCnt: p.WriteQueueLen(),
-					D:    cmd.Data,
-					ID:   cmd.ID,
-				}
-				switch {
-				case cmd.Queued:
-					res.Cmd = "Queued"
-				case cmd.Sent:
-					res.Cmd = "Write"
-				case cmd.Done:
-					res.Cmd = "Complete"
-				case cmd.Err != nil:
-				default:
-					log.Printf("unknown update from %s: %v", bufferType, cmd)
-					return
-				}
-
-				if cmd.Seq > 1 {
-					cmd.ID = fmt.Sprintf("%s-part-%d-%d", cmd.ID, cmd.Seq, cmd.SeqMax)
-				}
-
-				srv.respondJSON(res)
-			},
-		}),
+		name:    name,
+		cfg:     cfg,
+		info:    info,
+		primary: len(ports) == 0,
 	}
+	p.Buffer = buffer.NewBuffer(buffer.Config{
+		PollInterval:    cfg.PollInterval,
+		ReadWriteCloser: sp,
+		Handler:         srv.bufferTypeFns[cfg.BufferType](),
+		OnRead: func(line string) {
+			srv.publishJSON(name, classRaw, Response{
+				P: name,
+				D: line,
+			})
+		},
+		OnEvent: func(e buffer.Event) {
+			srv.publishJSON(name, classStatus, Response{
+				P:     name,
+				Event: e.Type,
+				Value: e.Value,
+			})
+		},
+		OnUpdate: func(cmd buffer.CommandResponse) {
+			if cmd.ID == "" {
+				return
+			}
+			res := Response{
+				P:    name,
+				QCnt: p.WriteQueueLen(),
+				D:    cmd.Data,
+				ID:   commandID(cmd),
+			}
+			switch {
+			case cmd.Queued:
+				res.Cmd = "Queued"
+			case cmd.Sent:
+				res.Cmd = "Write"
+			case cmd.Done:
+				res.Cmd = "Complete"
+			case cmd.Err != nil:
+				res.Cmd = "Error"
+				res.ErrorCode = cmd.Err.Error()
+			default:
+				log.Printf("unknown update from %s: %v", cfg.BufferType, cmd)
+				return
+			}
+
+			srv.publishJSON(name, classQueue, res)
+		},
+		OnError: func(err error) {
+			log.Printf("ERROR: port %s: %v", name, err)
+			srv.portLost(p, err)
+		},
+	})
 	ports[name] = p
 	srv.ports <- ports
 
-	return primary, nil
+	return p, nil
+}
+
+// commandID returns the ID reported for an update, identifying each line after
+// the first when a command was split into several.
+func commandID(cmd buffer.CommandResponse) string {
+	if cmd.Seq > 1 {
+		return fmt.Sprintf("%s-part-%d-%d", cmd.ID, cmd.Seq, cmd.SeqMax)
+	}
+	return cmd.ID
 }
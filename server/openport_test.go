@@ -0,0 +1,21 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/mastercactapus/yaspjs/buffer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandID(t *testing.T) {
+	check := func(seq, seqMax int, exp string) {
+		t.Helper()
+		cmd := buffer.CommandResponse{QueueItem: buffer.QueueItem{ID: "foo", Seq: seq, SeqMax: seqMax}}
+		assert.Equal(t, exp, commandID(cmd))
+	}
+
+	check(0, 0, "foo")
+	check(1, 3, "foo")
+	check(2, 3, "foo-part-2-3")
+	check(3, 3, "foo-part-3-3")
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// An OverflowPolicy determines what happens when a connection's outbound queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest will discard the oldest queued message, other than replies to
+	// the connection's own requests.
+	OverflowDropOldest OverflowPolicy = iota
+
+	// OverflowDropStatus will discard the oldest queued status or raw serial message,
+	// falling back to the oldest message if there are none.
+	OverflowDropStatus
+
+	// OverflowDisconnect will close the connection.
+	OverflowDisconnect
+)
+
+func (o OverflowPolicy) String() string {
+	switch o {
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowDropStatus:
+		return "drop-status"
+	case OverflowDisconnect:
+		return "disconnect"
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(o))
+}
+
+// ParseOverflowPolicy will parse the string representation of an OverflowPolicy.
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch s {
+	case "drop-oldest":
+		return OverflowDropOldest, nil
+	case "drop-status":
+		return OverflowDropStatus, nil
+	case "disconnect":
+		return OverflowDisconnect, nil
+	}
+	return OverflowDropOldest, fmt.Errorf("unknown overflow policy '%s'", s)
+}
+
+// outQueue is a bounded queue of messages waiting to be sent to a single connection.
+type outQueue struct {
+	mx     sync.Mutex
+	items  []message
+	max    int
+	policy OverflowPolicy
+	notify chan struct{}
+
+	dropped int64
+}
+
+func newOutQueue(max int, policy OverflowPolicy) *outQueue {
+	return &outQueue{
+		max:    max,
+		policy: policy,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// push will add a message without blocking. It reports if a message was dropped, and
+// returns false if the connection should be closed instead.
+//
+// Replies to the connection's own requests are never dropped, so the queue may exceed
+// its limit if it holds nothing else.
+func (q *outQueue) push(msg message) (dropped, ok bool) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	if len(q.items) >= q.max {
+		if q.policy == OverflowDisconnect {
+			atomic.AddInt64(&q.dropped, 1)
+			return true, false
+		}
+
+		i := q.oldest()
+		if q.policy == OverflowDropStatus {
+			i = q.oldestStatus()
+		}
+		if i == -1 && !msg.reply {
+			// only replies are queued, so drop the new message instead
+			atomic.AddInt64(&q.dropped, 1)
+			return true, true
+		}
+		if i != -1 {
+			q.dropAt(i)
+			atomic.AddInt64(&q.dropped, 1)
+			dropped = true
+		}
+	}
+	q.items = append(q.items, msg)
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return dropped, true
+}
+
+// oldest returns the index of the oldest message that is not a reply, or -1 if there are none.
+func (q *outQueue) oldest() int {
+	for i, msg := range q.items {
+		if !msg.reply {
+			return i
+		}
+	}
+	return -1
+}
+
+func (q *outQueue) oldestStatus() int {
+	for i, msg := range q.items {
+		if msg.class == classStatus || msg.class == classRaw {
+			return i
+		}
+	}
+	return q.oldest()
+}
+
+func (q *outQueue) dropAt(i int) {
+	q.items = append(q.items[:i], q.items[i+1:]...)
+}
+
+// shift will return all queued messages.
+func (q *outQueue) shift() []message {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	items := q.items
+	q.items = nil
+	return items
+}
+
+func (q *outQueue) Len() int {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	return len(q.items)
+}
+
+func (q *outQueue) Dropped() int64 { return atomic.LoadInt64(&q.dropped) }
+
+// ConnStats are the outbound queue metrics for a connection.
+type ConnStats struct {
+	ID      int32 `json:"Id"`
+	Queued  int
+	Dropped int64
+}
+
+func (srv *Server) handleStats(req request) {
+	conns := <-srv.conns
+	srv.conns <- conns
+
+	var res struct {
+		Conns        []ConnStats
+		TotalDropped int64
+	}
+	res.Conns = make([]ConnStats, 0, len(conns))
+	for _, c := range conns {
+		stats := ConnStats{ID: c.id, Queued: c.out.Len(), Dropped: c.out.Dropped()}
+		res.Conns = append(res.Conns, stats)
+	}
+	res.TotalDropped = atomic.LoadInt64(&srv.dropped)
+
+	req.respondJSON(res)
+}
+
+// enqueue will queue a message for the connection, closing it if the overflow policy requires.
+func (c *Conn) enqueue(msg message) {
+	dropped, ok := c.out.push(msg)
+	if dropped {
+		atomic.AddInt64(&c.srv.dropped, 1)
+	}
+	if ok {
+		return
+	}
+
+	if atomic.CompareAndSwapInt32(&c.kicked, 0, 1) {
+		log.Printf("ERROR: conn %d: outbound queue full, disconnecting", c.id)
+		c.Close()
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func queuedData(q *outQueue) []string {
+	var res []string
+	for _, msg := range q.shift() {
+		res = append(res, msg.data)
+	}
+	return res
+}
+
+func TestOutQueue_DropOldest(t *testing.T) {
+	q := newOutQueue(2, OverflowDropOldest)
+
+	q.push(message{data: "reply", reply: true})
+	q.push(message{data: "a"})
+	dropped, ok := q.push(message{data: "b"})
+	assert.True(t, dropped)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"reply", "b"}, queuedData(q))
+
+	// only replies queued
+	q.push(message{data: "reply1", reply: true})
+	q.push(message{data: "reply2", reply: true})
+	dropped, ok = q.push(message{data: "c"})
+	assert.True(t, dropped)
+	assert.True(t, ok)
+	dropped, ok = q.push(message{data: "reply3", reply: true})
+	assert.False(t, dropped)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"reply1", "reply2", "reply3"}, queuedData(q))
+	assert.EqualValues(t, 2, q.Dropped())
+}
+
+func TestOutQueue_DropStatus(t *testing.T) {
+	q := newOutQueue(3, OverflowDropStatus)
+
+	q.push(message{data: "reply", reply: true})
+	q.push(message{data: "a", class: classQueue})
+	q.push(message{data: "status", class: classStatus})
+	q.push(message{data: "b", class: classQueue})
+	assert.Equal(t, []string{"reply", "a", "b"}, queuedData(q))
+
+	q.push(message{data: "reply", reply: true})
+	q.push(message{data: "a", class: classQueue})
+	q.push(message{data: "b", class: classQueue})
+	q.push(message{data: "c", class: classQueue})
+	assert.Equal(t, []string{"reply", "b", "c"}, queuedData(q))
+}
+
+func TestOutQueue_Disconnect(t *testing.T) {
+	q := newOutQueue(1, OverflowDisconnect)
+
+	q.push(message{data: "a"})
+	dropped, ok := q.push(message{data: "b", reply: true})
+	assert.True(t, dropped)
+	assert.False(t, ok)
+}
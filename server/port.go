@@ -7,8 +7,10 @@ import (
 type Port struct {
 	*buffer.Buffer
 
-	name       string
-	bufferType string
-	baudRate   int
-	primary    bool
+	name    string
+	cfg     PortConfig
+	primary bool
+
+	// info is the identity of the device at open time, used to find it again when reconnecting.
+	info SerialPortInfo
 }
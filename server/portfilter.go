@@ -0,0 +1,55 @@
+package server
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// DefaultPortInclude is used if no include patterns are configured.
+var DefaultPortInclude = []string{"ttyACM*", "ttyUSB*", "ttyS*", "ttyAMA*", "ttymxc*", "rfcomm*"}
+
+// A PortFilter selects which devices are listed. Patterns are matched against the
+// device name (e.g. `ttyUSB0`) using filepath.Match. Patterns containing a `/` match
+// against the full path instead, and include patterns of that form (e.g. `/dev/pts/*`)
+// are also globbed, so that devices without sysfs entries, like pseudo-terminals, can
+// be listed.
+type PortFilter struct {
+	Include []string
+	Exclude []string
+}
+
+func matchAny(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		name := filepath.Base(path)
+		if strings.Contains(pattern, "/") {
+			name = path
+		}
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches will return true if the device at `path` (e.g. `/dev/ttyUSB0`) should be listed.
+func (f PortFilter) Matches(path string) bool {
+	return matchAny(f.Include, path) && !matchAny(f.Exclude, path)
+}
+
+// globPorts will return the ports matched by include patterns that are paths.
+func (f PortFilter) globPorts() []SerialPortInfo {
+	var res []SerialPortInfo
+	for _, pattern := range f.Include {
+		if !strings.Contains(pattern, "/") {
+			continue
+		}
+		matches, _ := filepath.Glob(pattern)
+		for _, path := range matches {
+			if !f.Matches(path) {
+				continue
+			}
+			res = append(res, SerialPortInfo{Name: path})
+		}
+	}
+	return res
+}
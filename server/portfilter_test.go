@@ -0,0 +1,22 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortFilter(t *testing.T) {
+	f := PortFilter{
+		Include: []string{"ttyUSB*", "ttyS*", "/dev/pts/*"},
+		Exclude: []string{"ttyS0", "/dev/pts/0"},
+	}
+
+	assert.True(t, f.Matches("/dev/ttyUSB0"))
+	assert.True(t, f.Matches("/dev/ttyS1"))
+	assert.True(t, f.Matches("/dev/pts/3"))
+	assert.False(t, f.Matches("/dev/ttyS0"))
+	assert.False(t, f.Matches("/dev/pts/0"))
+	assert.False(t, f.Matches("/dev/ttyACM0"))
+	assert.False(t, f.Matches("/dev/tty0"))
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"log"
+	"path/filepath"
+)
+
+// A Preset is the default configuration for a device, identified by serial
+// number or by a stable path (e.g. `/dev/serial/by-id/...`).
+type Preset struct {
+	SerialNumber string
+	Path         string
+
+	// Alias can be used in place of the device name (e.g. `open laser 115200`).
+	Alias string
+
+	PortConfig
+
+	// AutoOpen will open the device when AutoOpen is called (i.e. on start), and
+	// when it is plugged in.
+	AutoOpen bool
+}
+
+func (p Preset) matches(name string, info SerialPortInfo) bool {
+	if p.SerialNumber != "" && p.SerialNumber == info.SerialNumber {
+		return true
+	}
+	if p.Path == "" {
+		return false
+	}
+	if p.Path == name {
+		return true
+	}
+
+	target, err := filepath.EvalSymlinks(p.Path)
+	if err != nil {
+		return false
+	}
+	dev, err := filepath.EvalSymlinks(name)
+	return err == nil && target == dev
+}
+
+// preset will return the configured preset for the device, if any.
+func (srv *Server) preset(name string, info SerialPortInfo) (Preset, bool) {
+	for _, p := range srv.cfg.Presets {
+		if p.matches(name, info) {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// withPortDefaults will fill in unset values from the server config.
+func (srv *Server) withPortDefaults(cfg PortConfig) PortConfig {
+	if cfg.BufferType == "" {
+		cfg.BufferType = srv.cfg.DefaultBufferType
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = srv.cfg.PollInterval
+	}
+	if cfg.DataBits == 0 {
+		cfg.DataBits = 8
+	}
+	return cfg
+}
+
+// AutoOpen will open all connected devices that have a preset with AutoOpen set.
+// Devices that are not connected, or fail to open, are logged and skipped.
+func (srv *Server) AutoOpen() {
+	var auto []Preset
+	for _, p := range srv.cfg.Presets {
+		if p.AutoOpen {
+			auto = append(auto, p)
+		}
+	}
+	if len(auto) == 0 {
+		return
+	}
+
+	list, warnings, err := srv.ListPorts()
+	if err != nil {
+		log.Println("ERROR: auto-open: list ports:", err)
+		return
+	}
+	for _, w := range warnings {
+		log.Println("WARNING: auto-open: list ports:", w)
+	}
+
+	for _, p := range auto {
+		var found bool
+		for _, info := range list {
+			if !p.matches(info.Name, info) {
+				continue
+			}
+			found = true
+			srv.autoOpen(info, p.PortConfig)
+			break
+		}
+		if !found {
+			log.Printf("ERROR: auto-open: device not found (serial '%s', path '%s')", p.SerialNumber, p.Path)
+		}
+	}
+}
+
+func (srv *Server) autoOpen(info SerialPortInfo, cfg PortConfig) {
+	name := info.Name
+	cfg = srv.withPortDefaults(cfg)
+	primary, err := srv.openPortInfo(name, cfg, info)
+	if err != nil {
+		log.Printf("ERROR: auto-open %s: %v", name, err)
+		return
+	}
+
+	res := openResponse(name, srv.portAlias(name, info), cfg)
+	res.Desc = "Opened from preset."
+	res.IsPrimary = primary
+	srv.publishJSON(name, classPorts, res)
+}
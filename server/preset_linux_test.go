@@ -0,0 +1,32 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_AutoOpen(t *testing.T) {
+	_, name := openPTY(t)
+	stubListPorts(t, []SerialPortInfo{{Name: name, SerialNumber: "A123"}}, nil)
+	srv := newTestServer(t)
+	srv.cfg.Presets = []Preset{{
+		SerialNumber: "A123",
+		Alias:        "router",
+		AutoOpen:     true,
+		PortConfig:   PortConfig{Baud: 115200, BufferType: "grbl", DataBits: 7},
+	}}
+	c := newTestClient(t, srv, RoleFull)
+
+	srv.AutoOpen()
+	res := c.expect("open", func(r Response) bool { return r.Cmd == "Open" })
+
+	// same as the `open` response
+	exp := openResponse(name, "router", srv.withPortDefaults(srv.cfg.Presets[0].PortConfig))
+	exp.Desc = "Opened from preset."
+	exp.IsPrimary = true
+	assert.Equal(t, exp, res)
+	assert.Equal(t, "router", res.Alias)
+	assert.Equal(t, 7, res.DataBits)
+	assert.Equal(t, "none", res.Parity)
+}
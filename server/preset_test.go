@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreset_Matches(t *testing.T) {
+	dir := t.TempDir()
+	dev := filepath.Join(dir, "ttyUSB0")
+	link := filepath.Join(dir, "usb-FTDI-if00")
+	require.NoError(t, ioutil.WriteFile(dev, nil, 0644))
+	require.NoError(t, os.Symlink(dev, link))
+
+	info := SerialPortInfo{Name: dev, SerialNumber: "A123"}
+
+	assert.True(t, Preset{SerialNumber: "A123"}.matches(dev, info))
+	assert.False(t, Preset{SerialNumber: "B456"}.matches(dev, info))
+	assert.False(t, Preset{}.matches(dev, info), "empty preset")
+
+	assert.True(t, Preset{Path: dev}.matches(dev, info))
+	assert.True(t, Preset{Path: link}.matches(dev, info), "stable link")
+	assert.False(t, Preset{Path: filepath.Join(dir, "missing")}.matches(dev, info))
+	assert.False(t, Preset{Path: link}.matches(filepath.Join(dir, "ttyUSB1"), SerialPortInfo{}))
+}
+
+func TestServer_Preset(t *testing.T) {
+	srv := &Server{cfg: Config{Presets: []Preset{
+		{SerialNumber: "A123", Alias: "router"},
+		{Path: "/dev/ttyACM0", Alias: "printer"},
+	}}}
+
+	p, ok := srv.preset("/dev/ttyUSB3", SerialPortInfo{SerialNumber: "A123"})
+	assert.True(t, ok)
+	assert.Equal(t, "router", p.Alias)
+
+	p, ok = srv.preset("/dev/ttyACM0", SerialPortInfo{})
+	assert.True(t, ok)
+	assert.Equal(t, "printer", p.Alias)
+
+	_, ok = srv.preset("/dev/ttyACM1", SerialPortInfo{})
+	assert.False(t, ok)
+}
+
+func TestServer_CheckBufferTypes(t *testing.T) {
+	srv := NewServer(Config{WatchInterval: -1})
+	defer srv.Shutdown(context.Background())
+	assert.NoError(t, srv.CheckBufferTypes())
+
+	srv.cfg.DefaultBufferType = "grbl"
+	srv.cfg.Presets = []Preset{{Path: "/dev/ttyACM0", PortConfig: PortConfig{BufferType: "marlin"}}}
+	assert.NoError(t, srv.CheckBufferTypes())
+
+	srv.cfg.Presets[0].BufferType = "nope"
+	assert.EqualError(t, srv.CheckBufferTypes(), "preset 0: unknown buffer type 'nope'")
+
+	srv.cfg.DefaultBufferType = "grbll"
+	assert.EqualError(t, srv.CheckBufferTypes(), "unknown default buffer type 'grbll'")
+}
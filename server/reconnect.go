@@ -0,0 +1,216 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// A ReconnectPolicy determines how a port is recovered after it is lost.
+type ReconnectPolicy int
+
+const (
+	// ReconnectOff will close the port on error.
+	ReconnectOff ReconnectPolicy = iota
+
+	// ReconnectRetry will retry opening the same device name with backoff.
+	ReconnectRetry
+
+	// ReconnectSerial will retry with backoff, opening whichever device reports the
+	// same serial number, vendor and product ID as the original.
+	ReconnectSerial
+)
+
+// reconnect backoff, shortened by tests
+var (
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = 30 * time.Second
+)
+
+func (r ReconnectPolicy) String() string {
+	switch r {
+	case ReconnectOff:
+		return "off"
+	case ReconnectRetry:
+		return "retry"
+	case ReconnectSerial:
+		return "serial"
+	}
+	return fmt.Sprintf("ReconnectPolicy(%d)", int(r))
+}
+
+// ParseReconnectPolicy will parse the string representation of a ReconnectPolicy.
+func ParseReconnectPolicy(s string) (ReconnectPolicy, error) {
+	switch s {
+	case "off":
+		return ReconnectOff, nil
+	case "retry":
+		return ReconnectRetry, nil
+	case "serial":
+		return ReconnectSerial, nil
+	}
+	return ReconnectOff, fmt.Errorf("unknown reconnect policy '%s'", s)
+}
+
+func (srv *Server) portInfo(name string) (SerialPortInfo, error) {
+	info, _, err := srv.listPorts()
+	if err != nil {
+		return SerialPortInfo{}, err
+	}
+	for _, i := range info {
+		if i.Name == name {
+			return i, nil
+		}
+	}
+
+	return SerialPortInfo{}, errors.New("port not found")
+}
+
+// findDevice will return the name of the port matching the serial number, vendor and product ID of `info`.
+func (srv *Server) findDevice(info SerialPortInfo) (string, error) {
+	list, _, err := srv.listPorts()
+	if err != nil {
+		return "", err
+	}
+	for _, i := range list {
+		if i.SerialNumber == info.SerialNumber && i.VendorID == info.VendorID && i.ProductID == info.ProductID {
+			return i.Name, nil
+		}
+	}
+
+	return "", errors.New("device not found")
+}
+
+// cancelReconnect will stop any pending reconnect for the named port, returning
+// true if one was found.
+func (srv *Server) cancelReconnect(name string) bool {
+	reconnects := <-srv.reconnects
+	cancel := reconnects[name]
+	delete(reconnects, name)
+	srv.reconnects <- reconnects
+
+	if cancel == nil {
+		return false
+	}
+	close(cancel)
+	return true
+}
+
+// portLost will remove `p` after it failed with `err`, and start reconnecting if
+// enabled. Nothing is done if the port was already closed (e.g. by ClosePort).
+func (srv *Server) portLost(p *Port, err error) {
+	// reconnects is taken first (as in tryReconnect) so that ClosePort will always
+	// find either the port or its pending reconnect
+	reconnects := <-srv.reconnects
+	ports := <-srv.ports
+	lost := ports[p.name] == p
+	if lost {
+		delete(ports, p.name)
+	}
+	srv.ports <- ports
+	if !lost {
+		srv.reconnects <- reconnects
+		return
+	}
+
+	reconnect := p.cfg.Reconnect != ReconnectOff
+	select {
+	case <-srv.closing:
+		// shutting down, report the port as closed instead
+		reconnect = false
+	default:
+	}
+	cancel := make(chan struct{})
+	if reconnect {
+		reconnects[p.name] = cancel
+	}
+	srv.reconnects <- reconnects
+
+	if !reconnect {
+		srv.publishJSON(p.name, classPorts, Response{
+			Cmd:  "Close",
+			Desc: fmt.Sprintf("Port closed unexpectedly: %v", err),
+			Port: p.name,
+			Baud: p.cfg.Baud,
+		})
+		return
+	}
+
+	minDelay, maxDelay := reconnectMinDelay, reconnectMaxDelay
+	srv.publishJSON(p.name, classPorts, Response{
+		Cmd:  "Reconnecting",
+		Desc: fmt.Sprintf("Port lost, reconnecting: %v", err),
+		Port: p.name,
+		Baud: p.cfg.Baud,
+	})
+
+	go srv.reconnectLoop(p, cancel, minDelay, maxDelay)
+}
+
+func (srv *Server) reconnectLoop(p *Port, cancel chan struct{}, delay, maxDelay time.Duration) {
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-cancel:
+			return
+		case <-t.C:
+		}
+
+		newPort, err := srv.tryReconnect(p, cancel)
+		if err == nil {
+			srv.publishJSON(p.name, classPorts, Response{
+				Cmd:        "Reconnected",
+				Desc:       fmt.Sprintf("Reconnected port %s.", p.name),
+				Port:       newPort.name,
+				Baud:       newPort.cfg.Baud,
+				BufferType: newPort.cfg.BufferType,
+				IsPrimary:  newPort.primary,
+			})
+			return
+		}
+		if errors.Is(err, errReconnectCanceled) {
+			return
+		}
+
+		delay *= 2
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+		t.Reset(delay)
+	}
+}
+
+var errReconnectCanceled = errors.New("reconnect canceled")
+
+func (srv *Server) tryReconnect(p *Port, cancel chan struct{}) (*Port, error) {
+	name := p.name
+	if p.cfg.Reconnect == ReconnectSerial {
+		var err error
+		name, err = srv.findDevice(p.info)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// claim the reconnect so that a concurrent close or open can't race with it
+	reconnects := <-srv.reconnects
+	if reconnects[p.name] != cancel {
+		srv.reconnects <- reconnects
+		return nil, errReconnectCanceled
+	}
+	newPort, err := srv.openPort(name, p.cfg, p.info)
+	if err == nil {
+		delete(reconnects, p.name)
+	}
+	srv.reconnects <- reconnects
+
+	if err != nil {
+		log.Printf("ERROR: reconnect %s: %v", p.name, err)
+		return nil, err
+	}
+
+	return newPort, nil
+}
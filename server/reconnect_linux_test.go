@@ -0,0 +1,123 @@
+package server
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// hangup will replace the pty master with /dev/null, so the device reports an I/O
+// error. The fd stays valid for the cleanup from openPTY.
+func hangup(t *testing.T, master int) {
+	t.Helper()
+	null, err := unix.Open("/dev/null", unix.O_RDWR|unix.O_CLOEXEC, 0)
+	require.NoError(t, err)
+	defer unix.Close(null)
+	require.NoError(t, unix.Dup3(null, master, unix.O_CLOEXEC))
+}
+
+// fastReconnect will shorten the reconnect backoff for the test.
+func fastReconnect(t *testing.T) {
+	minDelay, maxDelay := reconnectMinDelay, reconnectMaxDelay
+	reconnectMinDelay, reconnectMaxDelay = 10*time.Millisecond, 20*time.Millisecond
+	t.Cleanup(func() { reconnectMinDelay, reconnectMaxDelay = minDelay, maxDelay })
+}
+
+// listedPorts is a stub for nativeListPorts that can be changed while the server runs.
+type listedPorts struct {
+	mx   sync.Mutex
+	info []SerialPortInfo
+}
+
+func stubListedPorts(t *testing.T, info ...SerialPortInfo) *listedPorts {
+	l := &listedPorts{info: info}
+	orig := nativeListPorts
+	nativeListPorts = func(PortFilter) ([]SerialPortInfo, []error, error) {
+		l.mx.Lock()
+		defer l.mx.Unlock()
+		return l.info, nil, nil
+	}
+	t.Cleanup(func() { nativeListPorts = orig })
+	return l
+}
+
+func (l *listedPorts) set(info ...SerialPortInfo) {
+	l.mx.Lock()
+	l.info = info
+	l.mx.Unlock()
+}
+
+func TestServer_Reconnect_Serial(t *testing.T) {
+	fastReconnect(t)
+	master, name := openPTY(t)
+	_, newName := openPTY(t)
+	list := stubListedPorts(t, SerialPortInfo{Name: name, SerialNumber: "A123"})
+	srv := newTestServer(t)
+	c := newTestClient(t, srv, RoleFull)
+
+	c.send("open " + name + " 115200 default serial")
+	c.expect("open", func(r Response) bool { return r.Cmd == "Open" })
+
+	// device is gone, and comes back later under a new path
+	list.set()
+	hangup(t, master)
+	res := c.expect("reconnecting", func(r Response) bool { return r.Cmd == "Reconnecting" })
+	assert.Equal(t, name, res.Port)
+	time.Sleep(50 * time.Millisecond)
+	list.set(SerialPortInfo{Name: newName, SerialNumber: "A123"})
+
+	res = c.expect("reconnected", func(r Response) bool { return r.Cmd == "Reconnected" })
+	assert.Equal(t, newName, res.Port)
+	assert.Equal(t, 115200, res.Baud)
+}
+
+func TestServer_Reconnect_Cancel(t *testing.T) {
+	fastReconnect(t)
+	master, name := openPTY(t)
+	stubListedPorts(t)
+	srv := newTestServer(t)
+	c := newTestClient(t, srv, RoleFull)
+
+	c.send("open " + name + " 115200 default retry")
+	c.expect("open", func(r Response) bool { return r.Cmd == "Open" })
+
+	// the pty is gone for good, so every retry fails
+	hangup(t, master)
+	c.expect("reconnecting", func(r Response) bool { return r.Cmd == "Reconnecting" })
+	time.Sleep(50 * time.Millisecond)
+
+	c.send("close " + name)
+	res := c.expect("close", func(r Response) bool { return r.Cmd == "Close" || r.Cmd == "CloseFail" })
+	assert.Equal(t, "Close", res.Cmd)
+	reconnects := <-srv.reconnects
+	srv.reconnects <- reconnects
+	assert.Empty(t, reconnects)
+
+	c.send("close " + name)
+	res = c.expect("close", func(r Response) bool { return r.Cmd == "Close" || r.Cmd == "CloseFail" })
+	assert.Equal(t, "CloseFail", res.Cmd, "nothing left to cancel")
+}
+
+func TestServer_ClosePort_NoReconnect(t *testing.T) {
+	master, name := openPTY(t)
+	stubListedPorts(t)
+	srv := newTestServer(t)
+	c := newTestClient(t, srv, RoleFull)
+
+	c.send("open " + name + " 115200 default retry")
+	c.expect("open", func(r Response) bool { return r.Cmd == "Open" })
+
+	// an explicit close wins over the I/O error it may cause
+	c.send("close " + name)
+	hangup(t, master)
+	c.expect("close", func(r Response) bool { return r.Cmd == "Close" })
+	time.Sleep(50 * time.Millisecond)
+
+	reconnects := <-srv.reconnects
+	srv.reconnects <- reconnects
+	assert.Empty(t, reconnects)
+}
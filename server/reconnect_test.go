@@ -0,0 +1,23 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_FindDevice(t *testing.T) {
+	stubListPorts(t, []SerialPortInfo{
+		{Name: "/dev/ttyUSB0", SerialNumber: "B456", VendorID: "1a86", ProductID: "7523"},
+		{Name: "/dev/ttyUSB3", SerialNumber: "A123", VendorID: "1a86", ProductID: "7523"},
+	}, nil)
+	srv := &Server{}
+
+	name, err := srv.findDevice(SerialPortInfo{Name: "/dev/ttyUSB1", SerialNumber: "A123", VendorID: "1a86", ProductID: "7523"})
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/ttyUSB3", name, "new path")
+
+	_, err = srv.findDevice(SerialPortInfo{Name: "/dev/ttyUSB1", SerialNumber: "A123", VendorID: "0403", ProductID: "6001"})
+	assert.EqualError(t, err, "device not found", "product must match")
+}
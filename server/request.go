@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+)
+
+// A request is a command from a single connection. Replies are only sent to
+// the requesting connection, tagged with the optional correlation id.
+type request struct {
+	conn *Conn
+
+	// id is set by prefixing a command with `#<id> ` and is returned as `ReqId`.
+	id string
+}
+
+// parseRequest will split an optional `#<id> ` prefix from `data`.
+func parseRequest(c *Conn, data string) (request, string) {
+	req := request{conn: c}
+	if !strings.HasPrefix(data, "#") {
+		return req, data
+	}
+
+	parts := strings.SplitN(data[1:], " ", 2)
+	req.id = parts[0]
+	if len(parts) == 1 {
+		return req, ""
+	}
+	return req, parts[1]
+}
+
+// reply will send `data` to the requesting connection only.
+func (req request) reply(data string) {
+	req.conn.enqueue(message{class: classSystem, data: data, reply: true})
+}
+
+func (req request) respondJSON(v interface{}) { req.reply(req.marshal(v)) }
+
+func (req request) respondErr(err error) {
+	if err == nil {
+		return
+	}
+	log.Printf("ERROR: conn %d: %v", req.conn.id, err)
+	var data struct {
+		Error string
+	}
+	data.Error = err.Error()
+
+	req.respondJSON(data)
+}
+
+// announceJSON will reply to the requesting connection and publish `v` as an
+// event to all other connections subscribed to `class` messages for `port`.
+func (req request) announceJSON(port string, class msgClass, v interface{}) {
+	req.respondJSON(v)
+
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		panic(err)
+	}
+	req.conn.srv.publish(message{port: port, class: class, data: string(data), from: req.conn})
+}
+
+func (req request) marshal(v interface{}) string {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		panic(err)
+	}
+	if req.id == "" {
+		return string(data)
+	}
+
+	var obj map[string]json.RawMessage
+	err = json.Unmarshal(data, &obj)
+	if err != nil {
+		panic(err)
+	}
+	obj["ReqId"], err = json.Marshal(req.id)
+	if err != nil {
+		panic(err)
+	}
+	data, err = json.MarshalIndent(obj, "", "\t")
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRequest(t *testing.T) {
+	check := func(input, expID, expData string) {
+		t.Helper()
+		req, data := parseRequest(nil, input)
+		assert.Equal(t, expID, req.id, input)
+		assert.Equal(t, expData, data, input)
+	}
+
+	check("list", "", "list")
+	check("#42 list", "42", "list")
+	check("#abc send /dev/ttyUSB0 G0 X1", "abc", "send /dev/ttyUSB0 G0 X1")
+	check("#42", "42", "")
+}
+
+func TestRequestMarshal(t *testing.T) {
+	var res struct {
+		Version string
+	}
+	res.Version = "1.0"
+
+	assert.JSONEq(t, `{"Version":"1.0"}`, request{}.marshal(res))
+	assert.JSONEq(t, `{"Version":"1.0","ReqId":"7"}`, request{id: "7"}.marshal(res))
+
+	data := request{id: "7"}.marshal(Response{Cmd: "Open", Port: "foo"})
+	var out Response
+	assert.NoError(t, json.Unmarshal([]byte(data), &out))
+	assert.Equal(t, "7", out.ReqID)
+}
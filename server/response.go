@@ -2,19 +2,26 @@ package server
 
 import (
 	"encoding/json"
-	"log"
+	"sync/atomic"
 )
 
 type Response struct {
 	SerialPorts []SerialPortInfo `json:",omitempty"`
+	Warnings    []string         `json:",omitempty"`
 	Cmd         string           `json:",omitempty"`
 	Desc        string           `json:",omitempty"`
 	Port        string           `json:",omitempty"`
+	Alias       string           `json:",omitempty"`
 
 	Baud       int    `json:",omitempty"`
 	BufferType string `json:",omitempty"`
 	IsPrimary  bool   `json:",omitempty"`
 
+	DataBits    int    `json:",omitempty"`
+	Parity      string `json:",omitempty"`
+	StopBits    string `json:",omitempty"`
+	FlowControl string `json:",omitempty"`
+
 	QCnt int
 
 	Data []struct {
@@ -25,25 +32,45 @@ type Response struct {
 	ID        string `json:"Id,omitempty"`
 	P, D      string `json:",omitempty"`
 	ErrorCode string `json:",omitempty"`
+
+	// Event is the type of structured data (e.g. `Status`) parsed by the buffer, stored in Value.
+	Event string      `json:",omitempty"`
+	Value interface{} `json:",omitempty"`
+
+	// ReqID is the correlation id of the command this is a reply to.
+	ReqID string `json:"ReqId,omitempty"`
+}
+
+// message is data to be sent to all connections subscribed to its port and class.
+type message struct {
+	port  string
+	class msgClass
+	data  string
+
+	// from, if set, is the connection the message was already sent to as a reply.
+	from *Conn
+
+	// reply is set for messages sent only to the requesting connection.
+	reply bool
 }
 
-func (srv *Server) respondJSON(v interface{}) {
+// publishJSON will send `v` to connections subscribed to `class` messages for `port`.
+func (srv *Server) publishJSON(port string, class msgClass, v interface{}) {
 	data, err := json.MarshalIndent(v, "", "\t")
 	if err != nil {
 		panic(err)
 	}
 
-	srv.send <- string(data)
+	srv.publish(message{port: port, class: class, data: string(data)})
 }
-func (srv *Server) respondErr(err error) {
-	if err == nil {
-		return
-	}
-	log.Println("ERROR:", err)
-	var data struct {
-		Error string
-	}
-	data.Error = err.Error()
 
-	srv.respondJSON(data)
+// publish will queue `msg` for delivery to subscribed connections. It is dropped
+// once the server has shut down.
+func (srv *Server) publish(msg message) {
+	atomic.AddInt32(&srv.sending, 1)
+	select {
+	case <-srv.done:
+		atomic.AddInt32(&srv.sending, -1)
+	case srv.send <- msg:
+	}
 }
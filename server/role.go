@@ -0,0 +1,42 @@
+package server
+
+import "fmt"
+
+// A Role determines which commands a connection may use.
+type Role int
+
+const (
+	// RoleFull allows all commands.
+	RoleFull Role = iota
+
+	// RoleReadOnly can observe ports, but not open, close or write to them.
+	RoleReadOnly
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleFull:
+		return "full"
+	case RoleReadOnly:
+		return "read-only"
+	}
+	return fmt.Sprintf("Role(%d)", int(r))
+}
+
+// readOnlyCommands are the commands allowed for RoleReadOnly.
+var readOnlyCommands = map[string]bool{
+	"list":             true,
+	"bufferalgorithms": true,
+	"baudrates":        true,
+	"stats":            true,
+	"subscribe":        true,
+	"version":          true,
+	"hostname":         true,
+}
+
+func (r Role) allows(cmd string) bool {
+	if r == RoleReadOnly {
+		return readOnlyCommands[cmd]
+	}
+	return true
+}
@@ -2,28 +2,46 @@ package server
 
 import (
 	"errors"
+	"fmt"
 	"strings"
+	"sync/atomic"
 )
 
-func (srv *Server) handleSend(argStr string) {
+func (srv *Server) handleSend(req request, argStr string, direct bool) {
 	if argStr == "" {
-		srv.respondErr(errors.New("missing port"))
+		req.respondErr(errors.New("missing port"))
 		return
 	}
 	parts := strings.SplitN(argStr, " ", 2)
 	if len(parts) == 1 {
-		srv.respondErr(errors.New("missing data"))
+		req.respondErr(errors.New("missing data"))
+		return
+	}
+
+	name, err := srv.resolvePort(parts[0])
+	if err != nil {
+		req.respondErr(err)
 		return
 	}
 
 	ports := <-srv.ports
-	p := ports[parts[0]]
+	p := ports[name]
 	srv.ports <- ports
 
 	if p == nil {
-		srv.respondErr(errors.New("specified port not open"))
+		req.respondErr(errors.New("specified port not open"))
+		return
+	}
+
+	if direct {
+		// updates are only published for commands with an ID
+		id := req.id
+		if id == "" {
+			id = fmt.Sprintf("nobuf-%d", atomic.AddInt64(&srv.nobufID, 1))
+		}
+		req.respondErr(p.QueueDirect(id, parts[1]))
 		return
 	}
 
-	srv.respondErr(p.Queue("", parts[1]))
+	req.respondErr(p.Queue("", parts[1]))
 }
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestServer_SendNoBuf(t *testing.T) {
+	master, name := openPTY(t)
+	srv := newTestServer(t)
+	c := newTestClient(t, srv, RoleFull)
+
+	c.send("open " + name + " 115200")
+	c.expect("open", func(r Response) bool { return r.Cmd == "Open" })
+
+	c.send("sendnobuf " + name + " G0 X1")
+	queued := c.expect("queued", func(r Response) bool { return r.Cmd == "Queued" })
+	assert.Equal(t, "nobuf-1", queued.ID)
+	assert.Equal(t, "G0 X1\n", queued.D)
+	written := c.expect("write", func(r Response) bool { return r.Cmd == "Write" })
+	assert.Equal(t, "nobuf-1", written.ID)
+
+	buf := make([]byte, 64)
+	n, err := unix.Read(master, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "G0 X1\n", string(buf[:n]))
+
+	// the request id is used if given
+	c.send("#abc sendnobuf " + name + " G0 X2")
+	queued = c.expect("queued", func(r Response) bool { return r.Cmd == "Queued" })
+	assert.Equal(t, "abc", queued.ID)
+}
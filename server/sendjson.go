@@ -5,28 +5,34 @@ import (
 	"errors"
 )
 
-func (srv *Server) handleSendJSON(argStr string) {
+func (srv *Server) handleSendJSON(req request, argStr string) {
 	// can use same format
-	var req Response
-	err := json.Unmarshal([]byte(argStr), &req)
+	var data Response
+	err := json.Unmarshal([]byte(argStr), &data)
 	if err != nil {
-		srv.respondErr(err)
+		req.respondErr(err)
+		return
+	}
+
+	name, err := srv.resolvePort(data.P)
+	if err != nil {
+		req.respondErr(err)
 		return
 	}
 
 	ports := <-srv.ports
-	p := ports[req.P]
+	p := ports[name]
 	srv.ports <- ports
 
 	if p == nil {
-		srv.respondErr(errors.New("specified port not open"))
+		req.respondErr(errors.New("specified port not open"))
 		return
 	}
 
-	for _, data := range req.Data {
-		err := p.Queue(data.ID, data.D)
+	for _, d := range data.Data {
+		err := p.Queue(d.ID, d.D)
 		if err != nil {
-			srv.respondErr(err)
+			req.respondErr(err)
 			return
 		}
 	}
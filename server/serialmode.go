@@ -0,0 +1,192 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"go.bug.st/serial"
+)
+
+// FlowControl is the flow control used by a port.
+type FlowControl int
+
+const (
+	// FlowNone disables flow control.
+	FlowNone FlowControl = iota
+
+	// FlowRTSCTS enables hardware (RTS/CTS) flow control.
+	FlowRTSCTS
+
+	// FlowXONXOFF enables software (XON/XOFF) flow control.
+	FlowXONXOFF
+)
+
+func (f FlowControl) String() string {
+	switch f {
+	case FlowNone:
+		return "none"
+	case FlowRTSCTS:
+		return "rtscts"
+	case FlowXONXOFF:
+		return "xonxoff"
+	}
+	return fmt.Sprintf("FlowControl(%d)", int(f))
+}
+
+// ParseFlowControl will parse the string representation of a FlowControl.
+func ParseFlowControl(s string) (FlowControl, error) {
+	switch strings.ToLower(s) {
+	case "none", "off":
+		return FlowNone, nil
+	case "rtscts", "hardware":
+		return FlowRTSCTS, nil
+	case "xonxoff", "software":
+		return FlowXONXOFF, nil
+	}
+	return FlowNone, fmt.Errorf("unknown flow control '%s'", s)
+}
+
+var parityNames = []string{
+	serial.NoParity:    "none",
+	serial.OddParity:   "odd",
+	serial.EvenParity:  "even",
+	serial.MarkParity:  "mark",
+	serial.SpaceParity: "space",
+}
+
+func parityString(p serial.Parity) string {
+	if int(p) < len(parityNames) {
+		return parityNames[p]
+	}
+	return fmt.Sprintf("Parity(%d)", int(p))
+}
+
+// ParseParity will parse a parity name (e.g. `even`) or its first letter (e.g. `E`).
+func ParseParity(s string) (serial.Parity, error) {
+	s = strings.ToLower(s)
+	for p, name := range parityNames {
+		if s == name || s == name[:1] {
+			return serial.Parity(p), nil
+		}
+	}
+	return serial.NoParity, fmt.Errorf("unknown parity '%s'", s)
+}
+
+var stopBitsNames = []string{
+	serial.OneStopBit:           "1",
+	serial.OnePointFiveStopBits: "1.5",
+	serial.TwoStopBits:          "2",
+}
+
+func stopBitsString(b serial.StopBits) string {
+	if int(b) < len(stopBitsNames) {
+		return stopBitsNames[b]
+	}
+	return fmt.Sprintf("StopBits(%d)", int(b))
+}
+
+// onePointFiveStopBits is false except on Windows, as go.bug.st/serial rejects 1.5
+// stop bits on every other platform.
+const onePointFiveStopBits = runtime.GOOS == "windows"
+
+// ParseStopBits will parse the number of stop bits (`1`, `1.5`, or `2`). 1.5 stop
+// bits are only supported on Windows.
+func ParseStopBits(s string) (serial.StopBits, error) {
+	for b, name := range stopBitsNames {
+		if s != name {
+			continue
+		}
+		if serial.StopBits(b) == serial.OnePointFiveStopBits && !onePointFiveStopBits {
+			return serial.OneStopBit, errors.New("1.5 stop bits unsupported on this platform")
+		}
+		return serial.StopBits(b), nil
+	}
+	return serial.OneStopBit, fmt.Errorf("invalid stop bits '%s'", s)
+}
+
+// ParseDataBits will parse the number of data bits (5-8).
+func ParseDataBits(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 5 || n > 8 {
+		return 0, fmt.Errorf("invalid data bits '%s'", s)
+	}
+	return n, nil
+}
+
+var modeRx = regexp.MustCompile(`^([5-8])([NOEMSnoems])(1|1\.5|2)$`)
+
+// setOption will set a `key=value` option from the `open` command:
+//
+//	databits=<5-8> parity=<none|odd|even|mark|space> stopbits=<1|1.5|2>
+//	flow=<none|rtscts|xonxoff> mode=<e.g. 7E1>
+func (cfg *PortConfig) setOption(key, value string) error {
+	var err error
+	switch strings.ToLower(key) {
+	case "databits":
+		cfg.DataBits, err = ParseDataBits(value)
+	case "parity":
+		cfg.Parity, err = ParseParity(value)
+	case "stopbits":
+		cfg.StopBits, err = ParseStopBits(value)
+	case "flow":
+		cfg.FlowControl, err = ParseFlowControl(value)
+	case "mode":
+		m := modeRx.FindStringSubmatch(value)
+		if m == nil {
+			return fmt.Errorf("invalid mode '%s'", value)
+		}
+		cfg.DataBits, _ = ParseDataBits(m[1])
+		cfg.Parity, _ = ParseParity(m[2])
+		cfg.StopBits, err = ParseStopBits(m[3])
+	default:
+		return fmt.Errorf("unknown option '%s'", key)
+	}
+	return err
+}
+
+// SetMode will parse and set the parity, stop bits and flow control. Empty
+// values are left unchanged.
+func (cfg *PortConfig) SetMode(parity, stopBits, flow string) error {
+	var err error
+	if parity != "" {
+		cfg.Parity, err = ParseParity(parity)
+		if err != nil {
+			return err
+		}
+	}
+	if stopBits != "" {
+		cfg.StopBits, err = ParseStopBits(stopBits)
+		if err != nil {
+			return err
+		}
+	}
+	if flow != "" {
+		cfg.FlowControl, err = ParseFlowControl(flow)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serialMode will return the mode to open the port with.
+func (cfg PortConfig) serialMode() *serial.Mode {
+	return &serial.Mode{
+		BaudRate: cfg.Baud,
+		DataBits: cfg.DataBits,
+		Parity:   cfg.Parity,
+		StopBits: cfg.StopBits,
+	}
+}
+
+// describeMode will set the serial mode fields of a Response.
+func (cfg PortConfig) describeMode(res *Response) {
+	res.DataBits = cfg.DataBits
+	res.Parity = parityString(cfg.Parity)
+	res.StopBits = stopBitsString(cfg.StopBits)
+	res.FlowControl = cfg.FlowControl.String()
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.bug.st/serial"
+)
+
+func TestPortConfigSetOption(t *testing.T) {
+	var cfg PortConfig
+	assert.NoError(t, cfg.setOption("mode", "7E1"))
+	assert.Equal(t, 7, cfg.DataBits)
+	assert.Equal(t, serial.EvenParity, cfg.Parity)
+	assert.Equal(t, serial.OneStopBit, cfg.StopBits)
+
+	assert.NoError(t, cfg.setOption("parity", "odd"))
+	assert.NoError(t, cfg.setOption("stopbits", "2"))
+	assert.NoError(t, cfg.setOption("databits", "8"))
+	assert.NoError(t, cfg.setOption("flow", "rtscts"))
+
+	var res Response
+	cfg.describeMode(&res)
+	assert.Equal(t, 8, res.DataBits)
+	assert.Equal(t, "odd", res.Parity)
+	assert.Equal(t, "2", res.StopBits)
+	assert.Equal(t, "rtscts", res.FlowControl)
+
+	assert.Error(t, cfg.setOption("databits", "9"))
+	assert.Error(t, cfg.setOption("parity", "x"))
+	assert.Error(t, cfg.setOption("stopbits", "3"))
+	assert.Error(t, cfg.setOption("flow", "dtr"))
+	assert.Error(t, cfg.setOption("mode", "8X1"))
+	assert.Error(t, cfg.setOption("speed", "fast"))
+}
+
+func TestParseStopBits(t *testing.T) {
+	b, err := ParseStopBits("2")
+	assert.NoError(t, err)
+	assert.Equal(t, serial.TwoStopBits, b)
+
+	var cfg PortConfig
+	if onePointFiveStopBits {
+		assert.NoError(t, cfg.setOption("stopbits", "1.5"))
+		assert.NoError(t, cfg.setOption("mode", "8N1.5"))
+		return
+	}
+	assert.Error(t, cfg.setOption("stopbits", "1.5"))
+	assert.Error(t, cfg.setOption("mode", "8N1.5"))
+	assert.Error(t, cfg.SetMode("", "1.5", ""))
+}
@@ -1,77 +1,98 @@
 package server
 
 import (
+	"sync/atomic"
+
 	"github.com/mastercactapus/yaspjs/buffer"
 )
 
 type Server struct {
+	dropped int64
+
+	// nobufID numbers `sendnobuf` commands sent without a request id.
+	nobufID int64
+
 	cid int32
 
+	// sending is the number of published messages not yet queued to connections.
+	sending int32
+
+	cfg Config
+
 	conns chan []*Conn
 
-	ports chan map[string]*Port
+	ports      chan map[string]*Port
+	reconnects chan map[string]chan struct{}
 
 	bufferTypeNames []string
 	bufferTypeFns   map[string]func() buffer.Handler
 
-	input chan string
-	send  chan string
-
-	newConn   chan *Conn
-	closeConn chan int32
+	input chan connInput
+	send  chan message
 
-	closeConnsCh chan struct{}
+	// closing is closed when Shutdown begins, loopDone once no more commands
+	// will be handled, and done once nothing more will be sent to connections.
+	closing  chan struct{}
+	loopDone chan struct{}
+	done     chan struct{}
 }
 
-func NewServer() *Server {
+func NewServer(cfg Config) *Server {
 	srv := &Server{
-		input:         make(chan string),
-		newConn:       make(chan *Conn),
-		closeConn:     make(chan int32),
-		closeConnsCh:  make(chan struct{}),
-		send:          make(chan string, 1),
+		cfg:           cfg.WithDefaults(),
+		input:         make(chan connInput),
+		send:          make(chan message, 1),
+		closing:       make(chan struct{}),
+		loopDone:      make(chan struct{}),
+		done:          make(chan struct{}),
 		conns:         make(chan []*Conn, 1),
 		ports:         make(chan map[string]*Port, 1),
+		reconnects:    make(chan map[string]chan struct{}, 1),
 		bufferTypeFns: make(map[string]func() buffer.Handler),
 	}
 	srv.conns <- nil
 	srv.ports <- make(map[string]*Port)
+	srv.reconnects <- make(map[string]chan struct{})
 	srv.defaultBufferTypes()
 
 	go srv.loop()
 	go srv.sendLoop()
+	if srv.cfg.WatchInterval > 0 {
+		go srv.watchPorts(srv.cfg.WatchInterval)
+	}
 	return srv
 }
 
 func (srv *Server) sendLoop() {
-	for data := range srv.send {
+	for {
+		var msg message
+		select {
+		case <-srv.done:
+			return
+		case msg = <-srv.send:
+		}
+
 		conns := <-srv.conns
 		srv.conns <- conns
 
 		for _, c := range conns {
-			c.send <- data
+			if c == msg.from || !c.sub.wants(msg) {
+				continue
+			}
+			c.enqueue(msg)
 		}
+		atomic.AddInt32(&srv.sending, -1)
 	}
 }
 
 func (srv *Server) loop() {
+	defer close(srv.loopDone)
 	for {
 		select {
-		case command := <-srv.input:
-			srv.handleCommand(command)
-		case c := <-srv.newConn:
-			conns := <-srv.conns
-			srv.conns <- append(conns, c)
-		case id := <-srv.closeConn:
-			origConns := <-srv.conns
-			conns := origConns[:0]
-			for _, c := range origConns {
-				if c.id == id {
-					continue
-				}
-				conns = append(conns, c)
-			}
-			srv.conns <- conns
+		case <-srv.closing:
+			return
+		case in := <-srv.input:
+			srv.handleCommand(in.conn, in.data)
 		}
 	}
 }
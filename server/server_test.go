@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	srv := NewServer(Config{WatchInterval: -1})
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	})
+	return srv
+}
+
+// testClient is a connection driven by a test.
+type testClient struct {
+	t *testing.T
+	*Conn
+}
+
+func newTestClient(t *testing.T, srv *Server, role Role) *testClient {
+	t.Helper()
+	c := &testClient{t: t, Conn: srv.NewConn(context.Background(), role)}
+	t.Cleanup(c.Close)
+	return c
+}
+
+func (c *testClient) send(cmd string) {
+	c.t.Helper()
+	select {
+	case c.FromClient() <- cmd:
+	case <-time.After(time.Second):
+		c.t.Fatalf("timeout sending '%s'", cmd)
+	}
+}
+
+// expect will read messages until one is a Response that `match` returns true for.
+func (c *testClient) expect(desc string, match func(Response) bool) Response {
+	c.t.Helper()
+	var res Response
+	c.expectRaw(desc, func(data string) bool {
+		res = Response{}
+		return json.Unmarshal([]byte(data), &res) == nil && match(res)
+	})
+	return res
+}
+
+// expectRaw will read messages until `match` returns true.
+func (c *testClient) expectRaw(desc string, match func(string) bool) {
+	c.t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case data := <-c.ToClient():
+			if match(data) {
+				return
+			}
+		case <-timeout:
+			c.t.Fatalf("timeout waiting for %s", desc)
+		}
+	}
+}
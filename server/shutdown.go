@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Shutdown will stop handling commands, close all ports (reporting any pending
+// commands as aborted) and then close all connections once their queued messages
+// have been delivered.
+//
+// If `ctx` expires first, remaining connections are closed immediately and the
+// context's error is returned.
+func (srv *Server) Shutdown(ctx context.Context) error {
+	select {
+	case <-srv.closing:
+		return nil
+	default:
+		close(srv.closing)
+	}
+	defer close(srv.done)
+
+	// wait for any in-progress command (e.g. an `open`) to finish
+	select {
+	case <-srv.loopDone:
+	case <-ctx.Done():
+	}
+
+	// portLost checks closing under the same lock, so none will be added after this
+	reconnects := <-srv.reconnects
+	for name, cancel := range reconnects {
+		close(cancel)
+		delete(reconnects, name)
+	}
+	srv.reconnects <- reconnects
+
+	ports := <-srv.ports
+	srv.ports <- make(map[string]*Port)
+	for name, p := range ports {
+		_, err := srv.closePort(p)
+		if err != nil {
+			log.Printf("ERROR: shutdown: %v", err)
+			continue
+		}
+		srv.publishJSON(name, classPorts, Response{
+			Cmd:  "Close",
+			Desc: "Server shutting down.",
+			Port: name,
+			Baud: p.cfg.Baud,
+		})
+	}
+
+	err := srv.drainConns(ctx)
+
+	conns := <-srv.conns
+	srv.conns <- conns
+	for _, c := range conns {
+		c.Close()
+	}
+
+	return err
+}
+
+// drainConns will wait until all messages published so far have been handed to
+// each connection's client.
+func (srv *Server) drainConns(ctx context.Context) error {
+	t := time.NewTicker(10 * time.Millisecond)
+	defer t.Stop()
+
+	for {
+		conns := <-srv.conns
+		srv.conns <- conns
+
+		pending := atomic.LoadInt32(&srv.sending) > 0
+		for _, c := range conns {
+			select {
+			case <-c.Done():
+				continue
+			default:
+			}
+			if c.out.Len() > 0 || len(c.send) > 0 {
+				pending = true
+			}
+		}
+		if !pending {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
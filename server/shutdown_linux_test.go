@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Shutdown(t *testing.T) {
+	_, name := openPTY(t)
+	srv := newTestServer(t)
+	c := newTestClient(t, srv, RoleFull)
+
+	c.send("open " + name + " 115200")
+	c.expect("open", func(r Response) bool { return r.Cmd == "Open" })
+
+	// messages queued before Shutdown are still delivered
+	msgs := make(chan Response, 10)
+	go func() {
+		defer close(msgs)
+		for {
+			select {
+			case <-c.Done():
+				return
+			case data := <-c.ToClient():
+				var res Response
+				if json.Unmarshal([]byte(data), &res) == nil {
+					msgs <- res
+				}
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, srv.Shutdown(ctx))
+
+	var closed bool
+	for res := range msgs {
+		if res.Cmd == "Close" && res.Port == name {
+			closed = true
+			assert.Equal(t, "Server shutting down.", res.Desc)
+		}
+	}
+	assert.True(t, closed, "port close reported")
+
+	ports := <-srv.ports
+	srv.ports <- ports
+	assert.Empty(t, ports)
+	select {
+	case <-c.Done():
+	default:
+		t.Error("connection not closed")
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_Shutdown_Timeout(t *testing.T) {
+	srv := newTestServer(t)
+	c := newTestClient(t, srv, RoleFull)
+
+	// never read, so the queue can't be drained
+	srv.publishJSON("", classSystem, Response{Cmd: "Test"})
+	srv.publishJSON("", classSystem, Response{Cmd: "Test"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := srv.Shutdown(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+
+	select {
+	case <-c.Done():
+	default:
+		t.Error("connection not closed")
+	}
+	assert.NoError(t, srv.Shutdown(context.Background()), "already shut down")
+
+	conn := srv.NewConn(context.Background(), RoleFull)
+	select {
+	case <-conn.Done():
+	default:
+		t.Error("connection accepted after shutdown")
+	}
+}
+
+func TestConn_Close_Busy(t *testing.T) {
+	stubListPorts(t, []SerialPortInfo{{Name: "/dev/ttyUSB0"}}, nil)
+	srv := newTestServer(t)
+	c := newTestClient(t, srv, RoleFull)
+
+	// `list` blocks the command loop until the ports are released
+	ports := <-srv.ports
+	c.send("list")
+
+	closed := make(chan struct{})
+	go func() {
+		c.Close()
+		<-c.Done()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked while the loop was busy")
+	}
+	srv.ports <- ports
+
+	// the loop is still usable by others
+	other := newTestClient(t, srv, RoleFull)
+	other.send("list")
+	other.expect("list", func(r Response) bool { return r.SerialPorts != nil })
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A msgClass identifies the kind of message being sent to clients so that
+// they can subscribe to only what they need.
+type msgClass int
+
+const (
+	// classSystem messages (broadcasts) are always sent. Replies to commands go
+	// directly to the requesting connection.
+	classSystem msgClass = iota
+
+	// classRaw is serial data read from a port.
+	classRaw
+
+	// classQueue is Queued/Write/Complete/Error updates for sent commands.
+	classQueue
+
+	// classStatus is structured events parsed by the buffer (e.g. Grbl status reports).
+	classStatus
+
+	// classPorts is ports being opened, closed or reconnected.
+	classPorts
+)
+
+var classNames = map[string]msgClass{
+	"raw":    classRaw,
+	"queue":  classQueue,
+	"status": classStatus,
+	"ports":  classPorts,
+}
+
+// subscription is the set of ports and classes a Conn will receive. A nil set means all.
+type subscription struct {
+	mx      sync.Mutex
+	ports   map[string]bool
+	classes map[msgClass]bool
+}
+
+func (s *subscription) wants(msg message) bool {
+	if msg.class == classSystem {
+		return true
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.classes != nil && !s.classes[msg.class] {
+		return false
+	}
+	if msg.port != "" && s.ports != nil && !s.ports[msg.port] {
+		return false
+	}
+	return true
+}
+
+func (s *subscription) set(ports map[string]bool, classes map[msgClass]bool) {
+	s.mx.Lock()
+	s.ports = ports
+	s.classes = classes
+	s.mx.Unlock()
+}
+
+// Subscription is the reply to the `subscribe` command.
+type Subscription struct {
+	Classes []string
+	Ports   []string
+}
+
+func (s *subscription) describe() Subscription {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	var res Subscription
+	for name, class := range classNames {
+		if s.classes == nil || s.classes[class] {
+			res.Classes = append(res.Classes, name)
+		}
+	}
+	for name := range s.ports {
+		res.Ports = append(res.Ports, name)
+	}
+	sort.Strings(res.Classes)
+	sort.Strings(res.Ports)
+	return res
+}
+
+// handleSubscribe will set the classes and ports a connection receives:
+//
+//	subscribe <all|class[,class...]> [port ...]
+//
+// Ports may be given by alias. With no ports, messages for all ports are received. With
+// no arguments, the current subscription is returned.
+func (srv *Server) handleSubscribe(req request, argStr string) {
+	args := strings.Fields(argStr)
+	if len(args) == 0 {
+		req.respondJSON(struct{ Subscription Subscription }{req.conn.sub.describe()})
+		return
+	}
+
+	var classes map[msgClass]bool
+	if args[0] != "all" {
+		classes = make(map[msgClass]bool)
+		for _, name := range strings.Split(args[0], ",") {
+			class, ok := classNames[name]
+			if !ok {
+				req.respondErr(fmt.Errorf("unknown message class '%s'", name))
+				return
+			}
+			classes[class] = true
+		}
+	}
+
+	var ports map[string]bool
+	if len(args) > 1 {
+		ports = make(map[string]bool, len(args)-1)
+		for _, name := range args[1:] {
+			// messages are published with the device name
+			dev, err := srv.resolvePort(name)
+			if err != nil {
+				req.respondErr(err)
+				return
+			}
+			ports[dev] = true
+		}
+	}
+
+	req.conn.sub.set(ports, classes)
+	req.respondJSON(struct{ Subscription Subscription }{req.conn.sub.describe()})
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscription_Wants(t *testing.T) {
+	var s subscription
+	assert.True(t, s.wants(message{class: classRaw, port: "/dev/ttyUSB0"}), "all by default")
+
+	s.set(map[string]bool{"/dev/ttyUSB0": true}, map[msgClass]bool{classQueue: true, classPorts: true})
+	for _, tc := range []struct {
+		desc string
+		msg  message
+		exp  bool
+	}{
+		{"system", message{class: classSystem, port: "/dev/ttyACM0"}, true},
+		{"subscribed", message{class: classQueue, port: "/dev/ttyUSB0"}, true},
+		{"other port", message{class: classQueue, port: "/dev/ttyACM0"}, false},
+		{"other class", message{class: classRaw, port: "/dev/ttyUSB0"}, false},
+		{"no port", message{class: classPorts}, true},
+		{"no port, other class", message{class: classStatus}, false},
+	} {
+		assert.Equal(t, tc.exp, s.wants(tc.msg), tc.desc)
+	}
+
+	s.set(nil, map[msgClass]bool{classRaw: true})
+	assert.True(t, s.wants(message{class: classRaw, port: "/dev/ttyACM0"}))
+	assert.Equal(t, Subscription{Classes: []string{"raw"}}, s.describe())
+}
+
+func TestServer_Subscribe(t *testing.T) {
+	stubListPorts(t, []SerialPortInfo{{Name: "/dev/ttyUSB1", SerialNumber: "A123"}}, nil)
+	srv := newTestServer(t)
+	srv.cfg.Presets = []Preset{{SerialNumber: "A123", Alias: "router"}}
+	c := newTestClient(t, srv, RoleFull)
+
+	subscription := func(cmd string) (Subscription, string) {
+		t.Helper()
+		c.send(cmd)
+		var res struct {
+			Subscription *Subscription
+			Error        string
+		}
+		c.expectRaw(cmd, func(data string) bool {
+			return json.Unmarshal([]byte(data), &res) == nil && (res.Subscription != nil || res.Error != "")
+		})
+		if res.Subscription == nil {
+			return Subscription{}, res.Error
+		}
+		return *res.Subscription, ""
+	}
+
+	sub, errMsg := subscription("subscribe queue,status router /dev/ttyS0")
+	require.Empty(t, errMsg)
+	assert.Equal(t, Subscription{Classes: []string{"queue", "status"}, Ports: []string{"/dev/ttyS0", "/dev/ttyUSB1"}}, sub)
+
+	srv.publishJSON("/dev/ttyUSB1", classRaw, Response{D: "raw"})
+	srv.publishJSON("/dev/ttyACM0", classQueue, Response{D: "other port"})
+	srv.publishJSON("/dev/ttyUSB1", classQueue, Response{D: "aliased"})
+	res := c.expect("update", func(r Response) bool { return r.D != "" })
+	assert.Equal(t, "aliased", res.D)
+
+	_, errMsg = subscription("subscribe bogus")
+	assert.Equal(t, "unknown message class 'bogus'", errMsg)
+	_, errMsg = subscription("subscribe all laser")
+	assert.Equal(t, "", errMsg, "not an alias, used as a device name")
+	srv.cfg.Presets = append(srv.cfg.Presets, Preset{SerialNumber: "B456", Alias: "printer"})
+	_, errMsg = subscription("subscribe all printer")
+	assert.Equal(t, "device for alias 'printer' not found", errMsg)
+}
@@ -0,0 +1,11 @@
+//go:build darwin || freebsd || openbsd
+// +build darwin freebsd openbsd
+
+package server
+
+import "golang.org/x/sys/unix"
+
+const (
+	ioctlGetTermios = unix.TIOCGETA
+	ioctlSetTermios = unix.TIOCSETA
+)
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"log"
+	"time"
+)
+
+// watchPorts will periodically list ports, publishing PortAdded and PortRemoved
+// events, and auto-opening added devices that have a preset with AutoOpen set.
+func (srv *Server) watchPorts(itvl time.Duration) {
+	if nativeListPorts == nil {
+		return
+	}
+
+	t := time.NewTicker(itvl)
+	defer t.Stop()
+
+	var known map[string]SerialPortInfo
+	for {
+		// warnings are reported by the `list` command instead, to avoid repeating them
+		list, _, err := srv.listPorts()
+		if err != nil {
+			log.Println("ERROR: watch ports:", err)
+		} else {
+			current := make(map[string]SerialPortInfo, len(list))
+			for _, info := range list {
+				current[info.Name] = info
+			}
+			if known != nil {
+				srv.diffPorts(known, current)
+			}
+			known = current
+		}
+
+		select {
+		case <-srv.closing:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (srv *Server) diffPorts(prev, current map[string]SerialPortInfo) {
+	for name, info := range prev {
+		if _, ok := current[name]; ok {
+			continue
+		}
+		info.Alias = srv.portAlias(name, info)
+		srv.publishJSON(name, classPorts, Response{
+			Cmd:         "PortRemoved",
+			Port:        name,
+			Alias:       info.Alias,
+			SerialPorts: []SerialPortInfo{info},
+		})
+	}
+
+	for name, info := range current {
+		if _, ok := prev[name]; ok {
+			continue
+		}
+		info.AvailableBufferAlgorithms = srv.bufferTypeNames
+		info.Alias = srv.portAlias(name, info)
+		srv.publishJSON(name, classPorts, Response{
+			Cmd:         "PortAdded",
+			Port:        name,
+			Alias:       info.Alias,
+			SerialPorts: []SerialPortInfo{info},
+		})
+
+		for _, p := range srv.cfg.Presets {
+			if p.AutoOpen && p.matches(name, info) {
+				srv.autoOpen(info, p.PortConfig)
+				break
+			}
+		}
+	}
+}
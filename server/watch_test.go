@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_DiffPorts(t *testing.T) {
+	srv := &Server{
+		send:            make(chan message, 10),
+		done:            make(chan struct{}),
+		bufferTypeNames: []string{"default"},
+		cfg: Config{Presets: []Preset{
+			{SerialNumber: "A123", Alias: "router"},
+			{SerialNumber: "B456", Alias: "printer"},
+		}},
+	}
+
+	prev := map[string]SerialPortInfo{
+		"/dev/ttyUSB0": {Name: "/dev/ttyUSB0", SerialNumber: "B456"},
+		"/dev/ttyUSB1": {Name: "/dev/ttyUSB1"},
+	}
+	current := map[string]SerialPortInfo{
+		"/dev/ttyUSB1": {Name: "/dev/ttyUSB1"},
+		"/dev/ttyACM0": {Name: "/dev/ttyACM0", SerialNumber: "A123"},
+	}
+	srv.diffPorts(prev, current)
+	require.Len(t, srv.send, 2)
+
+	var res []Response
+	for len(srv.send) > 0 {
+		msg := <-srv.send
+		assert.Equal(t, classPorts, msg.class)
+		var r Response
+		require.NoError(t, json.Unmarshal([]byte(msg.data), &r))
+		assert.Equal(t, msg.port, r.Port)
+		res = append(res, r)
+	}
+
+	assert.Equal(t, "PortRemoved", res[0].Cmd)
+	assert.Equal(t, "/dev/ttyUSB0", res[0].Port)
+	assert.Equal(t, "printer", res[0].Alias)
+	require.Len(t, res[0].SerialPorts, 1)
+	assert.Equal(t, "printer", res[0].SerialPorts[0].Alias)
+
+	assert.Equal(t, "PortAdded", res[1].Cmd)
+	assert.Equal(t, "/dev/ttyACM0", res[1].Port)
+	assert.Equal(t, "router", res[1].Alias)
+	require.Len(t, res[1].SerialPorts, 1)
+	assert.Equal(t, "router", res[1].SerialPorts[0].Alias, "same as `list`")
+	assert.Equal(t, "A123", res[1].SerialPorts[0].SerialNumber)
+	assert.Equal(t, []string{"default"}, res[1].SerialPorts[0].AvailableBufferAlgorithms)
+
+	srv.diffPorts(current, current)
+	assert.Empty(t, srv.send, "no changes")
+}